@@ -10,6 +10,8 @@ import (
 type INode interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // position of the first character of the node
+	End() token.Position // position immediately after the node
 }
 
 type IStatement interface {
@@ -42,19 +44,38 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
+
 // LetStatement
 // Basic Structure: let <identifier> = <expression>;
 type LetStatement struct {
-	Token token.Token // token.LET
-	Name  *Identifier
-	Value IExpression
+	Token   token.Token // token.LET
+	Name    *Identifier
+	Pattern IExpression // *ArrayPattern or *MapPattern when destructuring, Name is nil then
+	Value   IExpression
 }
 
 func (l *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(l.TokenLiteral() + " ")
-	out.WriteString(l.Name.String())
+	if l.Pattern != nil {
+		out.WriteString(l.Pattern.String())
+	} else {
+		out.WriteString(l.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if l.Value != nil {
@@ -69,6 +90,116 @@ func (l *LetStatement) statementNode() {}
 func (l *LetStatement) TokenLiteral() string {
 	return l.Token.Literal
 }
+func (l *LetStatement) Pos() token.Position {
+	return l.Token.Pos
+}
+func (l *LetStatement) End() token.Position {
+	if l.Value != nil {
+		return l.Value.End()
+	}
+	if l.Pattern != nil {
+		return l.Pattern.End()
+	}
+	return l.Name.End()
+}
+
+// ArrayPattern
+// Basic structure: [<target>, <target> = <default>, ...<identifier>]
+// Binds the targets to the items of an array in order, a target is an identifier or a
+// nested pattern. The rest identifier gets the items left over.
+type ArrayPattern struct {
+	Token    token.Token // token.L_SQR_BRACKET
+	Elements []*PatternElement
+	Rest     *Identifier
+	RBracket token.Token // token.R_SQR_BRACKET
+}
+
+func (a *ArrayPattern) expressionNode() {
+
+}
+
+func (a *ArrayPattern) TokenLiteral() string {
+	return a.Token.Literal
+}
+
+func (a *ArrayPattern) String() string {
+	var elements []string
+	for _, element := range a.Elements {
+		elements = append(elements, element.String())
+	}
+	if a.Rest != nil {
+		elements = append(elements, "..."+a.Rest.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+func (a *ArrayPattern) Pos() token.Position {
+	return a.Token.Pos
+}
+
+func (a *ArrayPattern) End() token.Position {
+	return a.RBracket.End
+}
+
+// MapPattern
+// Basic structure: {<key>, <key>: <target>, <key> = <default>}
+// Binds the targets to the values of a map stored under the keys, a key written on its own
+// binds the identifier of the same name.
+type MapPattern struct {
+	Token    token.Token // token.LBRACE
+	Elements []*PatternElement
+	RBrace   token.Token // token.RBRACE
+}
+
+func (m *MapPattern) expressionNode() {
+
+}
+
+func (m *MapPattern) TokenLiteral() string {
+	return m.Token.Literal
+}
+
+func (m *MapPattern) String() string {
+	var elements []string
+	for _, element := range m.Elements {
+		elements = append(elements, element.String())
+	}
+	return "{" + strings.Join(elements, ", ") + "}"
+}
+
+func (m *MapPattern) Pos() token.Position {
+	return m.Token.Pos
+}
+
+func (m *MapPattern) End() token.Position {
+	return m.RBrace.End
+}
+
+// PatternElement is a single binding of an ArrayPattern or a MapPattern. The default is
+// used when the destructured value has nothing, i.e. null, in its place.
+type PatternElement struct {
+	Key     *StringLiteral // the map key, nil in an ArrayPattern
+	Target  IExpression    // *Identifier, *ArrayPattern or *MapPattern
+	Default IExpression
+}
+
+func (p *PatternElement) String() string {
+	var out bytes.Buffer
+
+	if p.Key != nil {
+		out.WriteString(p.Key.String())
+		if ident, ok := p.Target.(*Identifier); !ok || ident.Value != p.Key.Value {
+			out.WriteString(": " + p.Target.String())
+		}
+	} else {
+		out.WriteString(p.Target.String())
+	}
+
+	if p.Default != nil {
+		out.WriteString(" = " + p.Default.String())
+	}
+	return out.String()
+}
 
 type Identifier struct {
 	Token token.Token // token.IDENT
@@ -83,6 +214,12 @@ func (i *Identifier) expressionNode() {}
 func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
+func (i *Identifier) Pos() token.Position {
+	return i.Token.Pos
+}
+func (i *Identifier) End() token.Position {
+	return i.Token.End
+}
 
 type ReturnStatement struct {
 	Token token.Token // token.RETURN
@@ -108,6 +245,15 @@ func (r *ReturnStatement) statementNode() {
 func (r *ReturnStatement) TokenLiteral() string {
 	return r.Token.Literal
 }
+func (r *ReturnStatement) Pos() token.Position {
+	return r.Token.Pos
+}
+func (r *ReturnStatement) End() token.Position {
+	if r.Value != nil {
+		return r.Value.End()
+	}
+	return r.Token.End
+}
 
 type ExpressionStatement struct {
 	Token token.Token
@@ -127,6 +273,18 @@ func (e *ExpressionStatement) statementNode() {
 func (e *ExpressionStatement) TokenLiteral() string {
 	return e.Token.Literal
 }
+func (e *ExpressionStatement) Pos() token.Position {
+	if e.Value != nil {
+		return e.Value.Pos()
+	}
+	return e.Token.Pos
+}
+func (e *ExpressionStatement) End() token.Position {
+	if e.Value != nil {
+		return e.Value.End()
+	}
+	return e.Token.End
+}
 
 type IntegerLiteral struct {
 	Token token.Token
@@ -144,6 +302,38 @@ func (i *IntegerLiteral) String() string {
 	return i.Token.Literal
 }
 
+func (i *IntegerLiteral) Pos() token.Position {
+	return i.Token.Pos
+}
+
+func (i *IntegerLiteral) End() token.Position {
+	return i.Token.End
+}
+
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (f *FloatLiteral) expressionNode() {
+
+}
+func (f *FloatLiteral) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (f *FloatLiteral) String() string {
+	return f.Token.Literal
+}
+
+func (f *FloatLiteral) Pos() token.Position {
+	return f.Token.Pos
+}
+
+func (f *FloatLiteral) End() token.Position {
+	return f.Token.End
+}
+
 type PrefixExpression struct {
 	Token    token.Token // prefix token i.e ! or -
 	Operator string
@@ -168,6 +358,17 @@ func (p *PrefixExpression) String() string {
 	return out.String()
 }
 
+func (p *PrefixExpression) Pos() token.Position {
+	return p.Token.Pos
+}
+
+func (p *PrefixExpression) End() token.Position {
+	if p.Value != nil {
+		return p.Value.End()
+	}
+	return p.Token.End
+}
+
 type InfixExpression struct {
 	Token      token.Token // operator token, e.g +, *
 	LeftValue  IExpression
@@ -194,6 +395,83 @@ func (i *InfixExpression) String() string {
 	return out.String()
 }
 
+func (i *InfixExpression) Pos() token.Position {
+	if i.LeftValue != nil {
+		return i.LeftValue.Pos()
+	}
+	return i.Token.Pos
+}
+
+func (i *InfixExpression) End() token.Position {
+	if i.RightValue != nil {
+		return i.RightValue.End()
+	}
+	return i.Token.End
+}
+
+// AssignExpression stores a new value in an existing binding or in an element of an array
+// or map, `x = v`, `m["k"] = v` or the compound `x += v`, `x -= v`, `x *= v`, `x /= v` and
+// `x %= v`. Target is an *Identifier or an *IndexExpression. It evaluates to the stored value.
+type AssignExpression struct {
+	Token    token.Token // operator token, e.g = or +=
+	Target   IExpression
+	Operator string
+	Value    IExpression
+}
+
+func (a *AssignExpression) expressionNode() {
+
+}
+
+func (a *AssignExpression) TokenLiteral() string {
+	return a.Token.Literal
+}
+
+func (a *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(a.Target.String())
+	out.WriteString(" " + a.Operator + " ")
+	out.WriteString(a.Value.String())
+
+	return out.String()
+}
+
+func (a *AssignExpression) Pos() token.Position {
+	return a.Target.Pos()
+}
+
+func (a *AssignExpression) End() token.Position {
+	if a.Value != nil {
+		return a.Value.End()
+	}
+	return a.Token.End
+}
+
+type NullLiteral struct {
+	Token token.Token // token.NULL
+}
+
+func (n *NullLiteral) expressionNode() {
+
+}
+
+func (n *NullLiteral) TokenLiteral() string {
+	return n.Token.Literal
+}
+
+func (n *NullLiteral) String() string {
+	return n.Token.Literal
+}
+
+func (n *NullLiteral) Pos() token.Position {
+	return n.Token.Pos
+}
+
+func (n *NullLiteral) End() token.Position {
+	return n.Token.End
+}
+
 type Boolean struct {
 	Token token.Token
 	Value bool
@@ -211,9 +489,18 @@ func (b *Boolean) String() string {
 	return b.Token.Literal
 }
 
+func (b *Boolean) Pos() token.Position {
+	return b.Token.Pos
+}
+
+func (b *Boolean) End() token.Position {
+	return b.Token.End
+}
+
 type BlockStatement struct {
 	Token      token.Token // token.LBRACE
 	Statements []IStatement
+	RBrace     token.Token // token.RBRACE
 }
 
 func (b *BlockStatement) statementNode() {
@@ -232,13 +519,29 @@ func (b *BlockStatement) String() string {
 	return out.String()
 }
 
+func (b *BlockStatement) Pos() token.Position {
+	return b.Token.Pos
+}
+
+func (b *BlockStatement) End() token.Position {
+	return b.RBrace.End
+}
+
 type IfExpression struct {
 	Token       token.Token // token.IF
 	Condition   IExpression
 	Consequence *BlockStatement
+	ElseIfs     []*ElseIf // `else if` clauses in source order
 	Alternative *BlockStatement
 }
 
+// ElseIf is an `else if (<condition>) <consequence>` clause of an IfExpression.
+type ElseIf struct {
+	Token       token.Token // token.IF
+	Condition   IExpression
+	Consequence *BlockStatement
+}
+
 func (i *IfExpression) expressionNode() {
 
 }
@@ -255,6 +558,13 @@ func (i *IfExpression) String() string {
 	out.WriteString(" ")
 	out.WriteString(i.Consequence.String())
 
+	for _, elseIf := range i.ElseIfs {
+		out.WriteString("else if")
+		out.WriteString(elseIf.Condition.String())
+		out.WriteString(" ")
+		out.WriteString(elseIf.Consequence.String())
+	}
+
 	if i.Alternative != nil {
 		out.WriteString("else ")
 		out.WriteString(i.Alternative.String())
@@ -263,10 +573,296 @@ func (i *IfExpression) String() string {
 	return out.String()
 }
 
+func (i *IfExpression) Pos() token.Position {
+	return i.Token.Pos
+}
+
+func (i *IfExpression) End() token.Position {
+	if i.Alternative != nil {
+		return i.Alternative.End()
+	}
+	if len(i.ElseIfs) > 0 {
+		return i.ElseIfs[len(i.ElseIfs)-1].Consequence.End()
+	}
+	return i.Consequence.End()
+}
+
+// MatchExpression
+// Basic structure: match (<expression>) { <pattern> => <body>, <pattern> if <guard> => <body> }
+// Evaluates to the body of the first arm whose pattern matches the subject and whose guard
+// holds, or to null when there is none.
+type MatchExpression struct {
+	Token   token.Token // token.MATCH
+	Subject IExpression
+	Arms    []*MatchArm
+	RBrace  token.Token // token.RBRACE
+}
+
+// MatchArm is one arm of a MatchExpression. The pattern is a literal, a binding identifier,
+// the wildcard `_`, an *ArrayPattern or a *MapPattern, the patterns of the last two nest
+// the same way. Guard is nil for an arm without one and Body is an expression or a
+// *BlockStatement.
+type MatchArm struct {
+	Pattern IExpression
+	Guard   IExpression
+	Body    INode
+}
+
+func (m *MatchExpression) expressionNode() {
+
+}
+
+func (m *MatchExpression) TokenLiteral() string {
+	return m.Token.Literal
+}
+
+func (m *MatchExpression) String() string {
+	var arms []string
+	for _, arm := range m.Arms {
+		arms = append(arms, arm.String())
+	}
+	return "match " + m.Subject.String() + " {" + strings.Join(arms, ", ") + "}"
+}
+
+func (m *MatchExpression) Pos() token.Position {
+	return m.Token.Pos
+}
+
+func (m *MatchExpression) End() token.Position {
+	return m.RBrace.End
+}
+
+func (m *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(m.Pattern.String())
+	if m.Guard != nil {
+		out.WriteString(" if " + m.Guard.String())
+	}
+	out.WriteString(" => " + m.Body.String())
+
+	return out.String()
+}
+
+// IsWildcard reports whether pattern is the wildcard `_`, which matches anything and binds
+// nothing.
+func IsWildcard(pattern IExpression) bool {
+	ident, ok := pattern.(*Identifier)
+	return ok && ident.Value == "_"
+}
+
+// TryExpression
+// Basic structure: try { <block> } catch (<identifier>) { <block> } finally { <block> }
+// Evaluates to the try block, or to the catch block when the try block throws. Either the
+// catch or the finally block may be left out, the parameter of catch is optional as well.
+// The finally block always runs last and its value is discarded.
+type TryExpression struct {
+	Token      token.Token // token.TRY
+	Block      *BlockStatement
+	CatchParam *Identifier     // binding of the caught error, nil when absent
+	Catch      *BlockStatement // nil without a catch block
+	Finally    *BlockStatement // nil without a finally block
+}
+
+func (t *TryExpression) expressionNode() {
+
+}
+
+func (t *TryExpression) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+func (t *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(t.Block.String())
+	if t.Catch != nil {
+		out.WriteString(" catch ")
+		if t.CatchParam != nil {
+			out.WriteString("(" + t.CatchParam.String() + ") ")
+		}
+		out.WriteString(t.Catch.String())
+	}
+	if t.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(t.Finally.String())
+	}
+
+	return out.String()
+}
+
+func (t *TryExpression) Pos() token.Position {
+	return t.Token.Pos
+}
+
+func (t *TryExpression) End() token.Position {
+	if t.Finally != nil {
+		return t.Finally.End()
+	}
+	return t.Catch.End()
+}
+
+type WhileStatement struct {
+	Token     token.Token // token.WHILE
+	Condition IExpression
+	Body      *BlockStatement
+}
+
+func (w *WhileStatement) statementNode() {
+
+}
+
+func (w *WhileStatement) TokenLiteral() string {
+	return w.Token.Literal
+}
+
+func (w *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while (")
+	out.WriteString(w.Condition.String())
+	out.WriteString(") ")
+	out.WriteString(w.Body.String())
+
+	return out.String()
+}
+
+func (w *WhileStatement) Pos() token.Position {
+	return w.Token.Pos
+}
+
+func (w *WhileStatement) End() token.Position {
+	return w.Body.End()
+}
+
+// ForStatement is a C-style `for (init; condition; post) { body }` loop. Init, Condition
+// and Post are nil when omitted, a missing condition loops until `break`.
+type ForStatement struct {
+	Token     token.Token // token.FOR
+	Init      IStatement
+	Condition IExpression
+	Post      IStatement
+	Body      *BlockStatement
+}
+
+func (f *ForStatement) statementNode() {
+
+}
+
+func (f *ForStatement) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (f *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	if f.Init != nil {
+		out.WriteString(strings.TrimSuffix(f.Init.String(), ";"))
+	}
+	out.WriteString("; ")
+	if f.Condition != nil {
+		out.WriteString(f.Condition.String())
+	}
+	out.WriteString("; ")
+	if f.Post != nil {
+		out.WriteString(strings.TrimSuffix(f.Post.String(), ";"))
+	}
+	out.WriteString(") ")
+	out.WriteString(f.Body.String())
+
+	return out.String()
+}
+
+func (f *ForStatement) Pos() token.Position {
+	return f.Token.Pos
+}
+
+func (f *ForStatement) End() token.Position {
+	return f.Body.End()
+}
+
+type BreakStatement struct {
+	Token token.Token // token.BREAK
+}
+
+func (b *BreakStatement) statementNode() {
+
+}
+
+func (b *BreakStatement) TokenLiteral() string {
+	return b.Token.Literal
+}
+
+func (b *BreakStatement) String() string {
+	return b.TokenLiteral() + ";"
+}
+
+func (b *BreakStatement) Pos() token.Position {
+	return b.Token.Pos
+}
+
+func (b *BreakStatement) End() token.Position {
+	return b.Token.End
+}
+
+type ContinueStatement struct {
+	Token token.Token // token.CONTINUE
+}
+
+func (c *ContinueStatement) statementNode() {
+
+}
+
+func (c *ContinueStatement) TokenLiteral() string {
+	return c.Token.Literal
+}
+
+func (c *ContinueStatement) String() string {
+	return c.TokenLiteral() + ";"
+}
+
+func (c *ContinueStatement) Pos() token.Position {
+	return c.Token.Pos
+}
+
+func (c *ContinueStatement) End() token.Position {
+	return c.Token.End
+}
+
+// ThrowStatement raises its value as an error, see TryExpression.
+type ThrowStatement struct {
+	Token token.Token // token.THROW
+	Value IExpression
+}
+
+func (t *ThrowStatement) statementNode() {
+
+}
+
+func (t *ThrowStatement) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+func (t *ThrowStatement) String() string {
+	return t.TokenLiteral() + " " + t.Value.String() + ";"
+}
+
+func (t *ThrowStatement) Pos() token.Position {
+	return t.Token.Pos
+}
+
+func (t *ThrowStatement) End() token.Position {
+	return t.Value.End()
+}
+
 type FunctionLiteral struct {
 	Name       string
 	Token      token.Token // token.FUNCTION
 	Parameters []*Identifier
+	Defaults   []IExpression // default value of each parameter, nil for a required one
+	Rest       *Identifier   // `...rest` parameter collecting the extra arguments, nil when absent
 	Body       *BlockStatement
 }
 
@@ -281,10 +877,7 @@ func (f *FunctionLiteral) TokenLiteral() string {
 func (f *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
-	var params []string
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
+	params := ParameterList(f.Parameters, f.Defaults, f.Rest)
 
 	out.WriteString(f.TokenLiteral())
 	if f.Name != "" {
@@ -298,10 +891,36 @@ func (f *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// ParameterList formats function parameters as written in the source, e.g. `a`, `b = 2` and
+// `...rest`. defaults is either nil or parallel to parameters.
+func ParameterList(parameters []*Identifier, defaults []IExpression, rest *Identifier) []string {
+	var params []string
+	for i, p := range parameters {
+		if i < len(defaults) && defaults[i] != nil {
+			params = append(params, p.String()+" = "+defaults[i].String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if rest != nil {
+		params = append(params, "..."+rest.String())
+	}
+	return params
+}
+
+func (f *FunctionLiteral) Pos() token.Position {
+	return f.Token.Pos
+}
+
+func (f *FunctionLiteral) End() token.Position {
+	return f.Body.End()
+}
+
 type CallExpression struct {
 	Token     token.Token // token.LPAREN
 	Func      IExpression //  FunctionLiteral or Identifier
 	Arguments []IExpression
+	RParen    token.Token // token.RPAREN
 }
 
 func (c *CallExpression) expressionNode() {
@@ -328,6 +947,14 @@ func (c *CallExpression) String() string {
 	return out.String()
 }
 
+func (c *CallExpression) Pos() token.Position {
+	return c.Func.Pos()
+}
+
+func (c *CallExpression) End() token.Position {
+	return c.RParen.End
+}
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -345,11 +972,91 @@ func (s *StringLiteral) String() string {
 	return s.Token.Literal
 }
 
+func (s *StringLiteral) Pos() token.Position {
+	return s.Token.Pos
+}
+
+func (s *StringLiteral) End() token.Position {
+	return s.Token.End
+}
+
+// InterpolatedString
+// Basic structure: "<text>${<expression>}<text>${<expression>}<text>"
+// Parts holds the embedded expressions in order with the text between them as StringLiterals,
+// empty text is left out.
+type InterpolatedString struct {
+	Token token.Token // token.STRING_HEAD
+	Parts []IExpression
+	Tail  token.Token // token.STRING_TAIL
+}
+
+func (i *InterpolatedString) expressionNode() {
+
+}
+
+func (i *InterpolatedString) TokenLiteral() string {
+	return i.Token.Literal
+}
+
+func (i *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("\"")
+	for _, part := range i.Parts {
+		if text, ok := part.(*StringLiteral); ok {
+			out.WriteString(text.Value)
+		} else {
+			out.WriteString("${" + part.String() + "}")
+		}
+	}
+	out.WriteString("\"")
+
+	return out.String()
+}
+
+func (i *InterpolatedString) Pos() token.Position {
+	return i.Token.Pos
+}
+
+func (i *InterpolatedString) End() token.Position {
+	return i.Tail.End
+}
+
+// SpreadElement
+// Basic structure: ...<expression>
+// Only valid as an item of an array literal or an argument of a call, where it stands for
+// all items of the array the expression evaluates to.
+type SpreadElement struct {
+	Token token.Token // token.ELLIPSIS
+	Value IExpression
+}
+
+func (s *SpreadElement) expressionNode() {
+
+}
+
+func (s *SpreadElement) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *SpreadElement) String() string {
+	return "..." + s.Value.String()
+}
+
+func (s *SpreadElement) Pos() token.Position {
+	return s.Token.Pos
+}
+
+func (s *SpreadElement) End() token.Position {
+	return s.Value.End()
+}
+
 // ArrayLiteral
 // Basic structure: [<expression>, <expression>, ...]
 type ArrayLiteral struct {
-	Token token.Token // token.L_SQR_BRACKET
-	Items []IExpression
+	Token    token.Token // token.L_SQR_BRACKET
+	Items    []IExpression
+	RBracket token.Token // token.R_SQR_BRACKET
 }
 
 func (a *ArrayLiteral) expressionNode() {
@@ -375,12 +1082,21 @@ func (a *ArrayLiteral) String() string {
 	return out.String()
 }
 
+func (a *ArrayLiteral) Pos() token.Position {
+	return a.Token.Pos
+}
+
+func (a *ArrayLiteral) End() token.Position {
+	return a.RBracket.End
+}
+
 // IndexExpression
 // Basic structure: <expression>[<expression>]
 type IndexExpression struct {
-	Token token.Token // token.L_SQR_BRACKET
-	Left  IExpression
-	Index IExpression
+	Token    token.Token // token.L_SQR_BRACKET
+	Left     IExpression
+	Index    IExpression
+	RBracket token.Token // token.R_SQR_BRACKET
 }
 
 func (i *IndexExpression) expressionNode() {
@@ -403,11 +1119,65 @@ func (i *IndexExpression) String() string {
 	return out.String()
 }
 
+func (i *IndexExpression) Pos() token.Position {
+	return i.Left.Pos()
+}
+
+func (i *IndexExpression) End() token.Position {
+	return i.RBracket.End
+}
+
+// SliceExpression
+// Basic structure: <expression>[<expression>:<expression>]
+// Low and High are nil when the bound is left out.
+type SliceExpression struct {
+	Token    token.Token // token.L_SQR_BRACKET
+	Left     IExpression
+	Low      IExpression
+	High     IExpression
+	RBracket token.Token // token.R_SQR_BRACKET
+}
+
+func (s *SliceExpression) expressionNode() {
+
+}
+
+func (s *SliceExpression) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(s.Left.String())
+	out.WriteString("[")
+	if s.Low != nil {
+		out.WriteString(s.Low.String())
+	}
+	out.WriteString(":")
+	if s.High != nil {
+		out.WriteString(s.High.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+func (s *SliceExpression) Pos() token.Position {
+	return s.Left.Pos()
+}
+
+func (s *SliceExpression) End() token.Position {
+	return s.RBracket.End
+}
+
 // MapLiteral
 // Basic structure: {<expression> : <expression>, <expression> : <expression>, ... }
 type MapLiteral struct {
-	Token token.Token // token.LBRACE
-	Pairs map[IExpression]IExpression
+	Token  token.Token // token.LBRACE
+	Pairs  map[IExpression]IExpression
+	RBrace token.Token // token.RBRACE
 }
 
 func (m *MapLiteral) expressionNode() {
@@ -432,3 +1202,11 @@ func (m *MapLiteral) String() string {
 
 	return out.String()
 }
+
+func (m *MapLiteral) Pos() token.Position {
+	return m.Token.Pos
+}
+
+func (m *MapLiteral) End() token.Position {
+	return m.RBrace.End
+}
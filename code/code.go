@@ -15,7 +15,8 @@ func (ins Instructions) String() string {
 	for i < len(ins) {
 		def, err := Lookup(ins[i])
 		if err != nil {
-			fmt.Fprintf(&out, "ERROR: %s", err)
+			fmt.Fprintf(&out, "%04d ERROR: %s\n", i, err)
+			i++
 			continue
 		}
 
@@ -81,6 +82,26 @@ const (
 	OpClosure
 	OpGetFree
 	OpCurrentClosure
+	OpLessThan
+	OpLessThanOrEqual
+	OpGreaterThanOrEqual
+	OpJumpTruthy
+	OpMod
+	OpSetFree
+	OpGetLocalCell
+	OpGetFreeCell
+	OpSetIndex
+	OpDuplicatePair
+	OpConcat
+	OpSlice
+	OpHasArgument
+	OpExtendArray
+	OpCallSpread
+	OpMatchArray
+	OpMatchMap
+	OpTry
+	OpEndTry
+	OpThrow
 )
 
 type OpcodeDefinition struct {
@@ -209,6 +230,97 @@ var definitions = map[Opcode]*OpcodeDefinition{
 		Name:          "OpCurrentClosure",
 		OperandWidths: []int{},
 	},
+	OpLessThan: {
+		Name:          "OpLessThan",
+		OperandWidths: []int{},
+	},
+	OpLessThanOrEqual: {
+		Name:          "OpLessThanOrEqual",
+		OperandWidths: []int{},
+	},
+	OpGreaterThanOrEqual: {
+		Name:          "OpGreaterThanOrEqual",
+		OperandWidths: []int{},
+	},
+	OpJumpTruthy: {
+		Name:          "OpJumpTruthy",
+		OperandWidths: []int{2},
+	},
+	OpMod: {
+		Name:          "OpMod",
+		OperandWidths: []int{},
+	},
+	OpSetFree: {
+		Name:          "OpSetFree",
+		OperandWidths: []int{1},
+	},
+	OpGetLocalCell: {
+		Name:          "OpGetLocalCell",
+		OperandWidths: []int{1},
+	},
+	OpGetFreeCell: {
+		Name:          "OpGetFreeCell",
+		OperandWidths: []int{1},
+	},
+	OpSetIndex: {
+		Name:          "OpSetIndex",
+		OperandWidths: []int{},
+	},
+	OpDuplicatePair: {
+		Name:          "OpDuplicatePair",
+		OperandWidths: []int{},
+	},
+	OpConcat: {
+		Name:          "OpConcat",
+		OperandWidths: []int{2}, // number of values joined into one string
+	},
+	OpSlice: {
+		Name:          "OpSlice",
+		OperandWidths: []int{},
+	},
+	OpHasArgument: {
+		Name:          "OpHasArgument",
+		OperandWidths: []int{1}, // local index of the parameter
+	},
+	OpExtendArray: {
+		Name:          "OpExtendArray",
+		OperandWidths: []int{},
+	},
+	OpCallSpread: {
+		Name:          "OpCallSpread",
+		OperandWidths: []int{}, // the arguments come as one array
+	},
+	OpMatchArray: {
+		Name:          "OpMatchArray",
+		OperandWidths: []int{2, 1}, // number of items, whether there may be more of them
+	},
+	OpMatchMap: {
+		Name:          "OpMatchMap",
+		OperandWidths: []int{2}, // number of keys the map must have
+	},
+	OpTry: {
+		Name:          "OpTry",
+		OperandWidths: []int{2}, // where the handler continues with the error value
+	},
+	OpEndTry: {
+		Name:          "OpEndTry",
+		OperandWidths: []int{},
+	},
+	OpThrow: {
+		Name:          "OpThrow",
+		OperandWidths: []int{},
+	},
+}
+
+// IsJump reports whether op may transfer control to the absolute instruction offset held in
+// its first operand. OpTry does so when an error is raised before the matching OpEndTry.
+func IsJump(op Opcode) bool {
+	switch op {
+	case OpJump, OpJumpNotTruthy, OpJumpTruthy, OpTry:
+		return true
+	default:
+		return false
+	}
 }
 
 func Lookup(op byte) (*OpcodeDefinition, error) {
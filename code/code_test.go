@@ -1,6 +1,61 @@
 package code
 
-import "testing"
+import (
+	"BigTalk_Interpreter/token"
+	"testing"
+)
+
+func TestSourceMap_Lookup(t *testing.T) {
+	sourceMap := SourceMap{
+		{Offset: 0, Pos: token.Position{Line: 1, Column: 1}},
+		{Offset: 3, Pos: token.Position{Line: 1, Column: 5}},
+		{Offset: 7, Pos: token.Position{Line: 2, Column: 1}},
+	}
+
+	testCases := []struct {
+		offset   int
+		expected string
+	}{
+		{0, "1:1"},
+		{2, "1:1"},
+		{3, "1:5"},
+		{6, "1:5"},
+		{7, "2:1"},
+		{100, "2:1"},
+	}
+
+	for _, tc := range testCases {
+		pos, ok := sourceMap.Lookup(tc.offset)
+		if !ok || pos.String() != tc.expected {
+			t.Errorf("Lookup(%d) = %s, %t, want = %s, true", tc.offset, pos, ok, tc.expected)
+		}
+	}
+
+	if _, ok := (SourceMap{}).Lookup(0); ok {
+		t.Errorf("Lookup on an empty source map reported a position")
+	}
+
+	truncated := sourceMap.Truncate(3)
+	if len(truncated) != 1 {
+		t.Errorf("len(Truncate(3)) = %d, want = 1", len(truncated))
+	}
+}
+
+func TestInstructions_String_UnknownOpcode(t *testing.T) {
+	concatenated := Instructions{}
+	concatenated = append(concatenated, MakeInstruction(OpAdd)...)
+	concatenated = append(concatenated, 255)
+	concatenated = append(concatenated, MakeInstruction(OpPop)...)
+
+	expected := `0000 OpAdd
+0001 ERROR: opcode 255 undefined
+0002 OpPop
+`
+
+	if concatenated.String() != expected {
+		t.Errorf("wrongly formatted instructions. \n got = %q, \n want = %q", concatenated.String(), expected)
+	}
+}
 
 func TestMakeInstruction(t *testing.T) {
 	testCases := []struct {
@@ -0,0 +1,32 @@
+package code
+
+import (
+	"BigTalk_Interpreter/token"
+	"sort"
+)
+
+// SourceMapping records that the instructions starting at Offset were compiled from the
+// source found at Pos.
+type SourceMapping struct {
+	Offset int
+	Pos    token.Position
+}
+
+// SourceMap maps instruction offsets back to source positions. Entries are sorted by
+// offset and each one covers every instruction up to the next entry.
+type SourceMap []SourceMapping
+
+// Lookup returns the source position of the instruction containing the given offset.
+func (m SourceMap) Lookup(offset int) (token.Position, bool) {
+	i := sort.Search(len(m), func(i int) bool { return m[i].Offset > offset })
+	if i == 0 {
+		return token.Position{}, false
+	}
+	return m[i-1].Pos, true
+}
+
+// Truncate drops the entries for instructions at or after the given offset.
+func (m SourceMap) Truncate(offset int) SourceMap {
+	i := sort.Search(len(m), func(i int) bool { return m[i].Offset >= offset })
+	return m[:i]
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"BigTalk_Interpreter/compiler"
+	"BigTalk_Interpreter/engine"
+	"BigTalk_Interpreter/vm"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const byteCodeExtension = ".btc"
+
+// compileCommand implements `bigtalk compile <file> [-o <output>]`. It writes the bytecode
+// of the script in the serialized format so it can later be run with `bigtalk exec`.
+func compileCommand(args []string) int {
+	fs := newFlagSet("compile")
+	output := fs.String("o", "", "output file (default: the input file with a "+byteCodeExtension+" extension)")
+
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(files) != 1 {
+		fmt.Fprintf(os.Stderr, "compile: expected exactly one file argument\n\n%s", usage)
+		return 2
+	}
+	path := files[0]
+
+	outputPath := *output
+	if outputPath == "" {
+		outputPath = strings.TrimSuffix(path, filepath.Ext(path)) + byteCodeExtension
+	}
+
+	program, ok := parseSourceFile(path)
+	if !ok {
+		return 1
+	}
+
+	comp := compiler.NewCompiler()
+	err = comp.Compile(program)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: compilation error: %s\n", path, err)
+		return 1
+	}
+
+	data, err := comp.ByteCode().MarshalBinary()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: compilation error: %s\n", path, err)
+		return 1
+	}
+
+	err = os.WriteFile(outputPath, data, 0o644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "compile: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// execCommand implements `bigtalk exec <file.btc>` and runs precompiled bytecode on the VM.
+func execCommand(args []string) int {
+	fs := newFlagSet("exec")
+
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(files) != 1 {
+		fmt.Fprintf(os.Stderr, "exec: expected exactly one file argument\n\n%s", usage)
+		return 2
+	}
+	path := files[0]
+
+	bytecode, err := readByteCodeFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "exec: %s\n", err)
+		return 1
+	}
+
+	vMachine := vm.NewVirtualMachine(bytecode)
+	err = vMachine.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: runtime error: %s\n%s", path, err, engine.StackTrace(err))
+		return 1
+	}
+	return 0
+}
+
+func readByteCodeFile(path string) (*compiler.ByteCode, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	bytecode := &compiler.ByteCode{}
+	err = bytecode.UnmarshalBinary(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return bytecode, nil
+}
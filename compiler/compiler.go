@@ -4,13 +4,16 @@ import (
 	"BigTalk_Interpreter/ast"
 	"BigTalk_Interpreter/code"
 	"BigTalk_Interpreter/object"
+	"BigTalk_Interpreter/token"
 	"fmt"
+	"math"
 	"sort"
 )
 
 type ByteCode struct {
 	Instructions code.Instructions
 	Constants    []object.IObject
+	SourceMap    code.SourceMap // source positions of Instructions
 }
 
 type EmittedInstructions struct {
@@ -25,6 +28,13 @@ type Compiler struct {
 
 	scopes     []CompilationScope
 	scopeIndex int
+
+	loops []*loopContext // loops enclosing the node being compiled, innermost last
+	tries []*tryContext  // try statements whose handler guards the node being compiled, innermost last
+
+	patterns int // hidden bindings in use by the destructuring being compiled
+
+	position token.Position // source position of the node being compiled
 }
 
 func NewCompiler() *Compiler {
@@ -54,6 +64,12 @@ func NewCompilerWithState(s *SymbolTable, constants []object.IObject) *Compiler
 }
 
 func (c *Compiler) Compile(node ast.INode) error {
+	previous := c.position
+	if pos := sourcePosition(node); pos.IsValid() {
+		c.position = pos
+	}
+	defer func() { c.position = previous }()
+
 	switch node := node.(type) {
 	case *ast.Program:
 		for _, s := range node.Statements {
@@ -69,18 +85,10 @@ func (c *Compiler) Compile(node ast.INode) error {
 		}
 		c.emit(code.OpPop)
 	case *ast.InfixExpression:
-		if node.Operator == "<" {
-			err := c.Compile(node.RightValue)
-			if err != nil {
-				return err
-			}
-			err = c.Compile(node.LeftValue)
-			if err != nil {
-				return err
-			}
-			c.emit(code.OpGreaterThan)
-			return nil
+		if node.Operator == "&&" || node.Operator == "||" {
+			return c.compileLogicalExpression(node)
 		}
+
 		err := c.Compile(node.LeftValue)
 		if err != nil {
 			return err
@@ -100,8 +108,16 @@ func (c *Compiler) Compile(node ast.INode) error {
 			c.emit(code.OpMul)
 		case "/":
 			c.emit(code.OpDiv)
+		case "%":
+			c.emit(code.OpMod)
+		case "<":
+			c.emit(code.OpLessThan)
+		case "<=":
+			c.emit(code.OpLessThanOrEqual)
 		case ">":
 			c.emit(code.OpGreaterThan)
+		case ">=":
+			c.emit(code.OpGreaterThanOrEqual)
 		case "==":
 			c.emit(code.OpEqual)
 		case "!=":
@@ -112,6 +128,11 @@ func (c *Compiler) Compile(node ast.INode) error {
 	case *ast.IntegerLiteral:
 		integer := &object.Integer{Value: node.Value}
 		c.emit(code.OpConstant, c.addConstant(integer))
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
+	case *ast.NullLiteral:
+		c.emit(code.OpNull)
 	case *ast.Boolean:
 		if node.Value {
 			c.emit(code.OpTrue)
@@ -133,40 +154,31 @@ func (c *Compiler) Compile(node ast.INode) error {
 			return fmt.Errorf("unknown operator %s", node.Operator)
 		}
 	case *ast.IfExpression:
-		err := c.Compile(node.Condition)
-		if err != nil {
-			return err
+		return c.compileIfExpression(node)
+	case *ast.WhileStatement:
+		return c.compileWhileStatement(node)
+	case *ast.ForStatement:
+		return c.compileForStatement(node)
+	case *ast.BreakStatement:
+		if len(c.loops) == 0 {
+			return fmt.Errorf("break outside of a loop")
 		}
-
-		jumpNotTruthyPosition := c.emit(code.OpJumpNotTruthy, 999)
-
-		err = c.Compile(node.Consequence)
+		loop := c.loops[len(c.loops)-1]
+		err := c.exitTries(loop.tries)
 		if err != nil {
 			return err
 		}
-
-		if c.lastInstructionIs(code.OpPop) {
-			c.removeLastPop()
+		loop.breaks = append(loop.breaks, c.emit(code.OpJump, 999))
+	case *ast.ContinueStatement:
+		if len(c.loops) == 0 {
+			return fmt.Errorf("continue outside of a loop")
 		}
-
-		jumpPosition := c.emit(code.OpJump, 999)
-		afterConsequencePosition := len(c.currentInstructions())
-		c.changeOperand(jumpNotTruthyPosition, afterConsequencePosition)
-
-		if node.Alternative == nil {
-			c.emit(code.OpNull)
-		} else {
-			err := c.Compile(node.Alternative)
-			if err != nil {
-				return err
-			}
-
-			if c.lastInstructionIs(code.OpPop) {
-				c.removeLastPop()
-			}
+		loop := c.loops[len(c.loops)-1]
+		err := c.exitTries(loop.tries)
+		if err != nil {
+			return err
 		}
-		afterAlternativePosition := len(c.currentInstructions())
-		c.changeOperand(jumpPosition, afterAlternativePosition)
+		loop.continues = append(loop.continues, c.emit(code.OpJump, 999))
 	case *ast.BlockStatement:
 		for _, s := range node.Statements {
 			err := c.Compile(s)
@@ -174,18 +186,29 @@ func (c *Compiler) Compile(node ast.INode) error {
 				return err
 			}
 		}
-	case *ast.LetStatement:
-		symbol := c.symbolTable.Define(node.Name.Value)
+	case *ast.MatchExpression:
+		return c.compileMatchExpression(node)
+	case *ast.TryExpression:
+		return c.compileTryExpression(node)
+	case *ast.ThrowStatement:
 		err := c.Compile(node.Value)
 		if err != nil {
 			return err
 		}
+		c.emit(code.OpThrow)
+	case *ast.LetStatement:
+		if node.Pattern != nil {
+			return c.compileDestructuring(node)
+		}
 
-		if symbol.Scope == GlobalScope {
-			c.emit(code.OpSetGlobal, symbol.Index)
-		} else {
-			c.emit(code.OpSetLocal, symbol.Index)
+		symbol := c.symbolTable.Define(node.Name.Value)
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
 		}
+		c.storeSymbol(symbol)
+	case *ast.AssignExpression:
+		return c.compileAssignExpression(node)
 	case *ast.Identifier:
 		symbol, ok := c.symbolTable.Resolve(node.Value)
 		if !ok {
@@ -195,7 +218,19 @@ func (c *Compiler) Compile(node ast.INode) error {
 	case *ast.StringLiteral:
 		str := &object.String{Value: node.Value}
 		c.emit(code.OpConstant, c.addConstant(str))
+	case *ast.InterpolatedString:
+		for _, part := range node.Parts {
+			err := c.Compile(part)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpConcat, len(node.Parts))
 	case *ast.ArrayLiteral:
+		if hasSpread(node.Items) {
+			return c.compileSpreadList(node.Items)
+		}
+
 		for _, item := range node.Items {
 			err := c.Compile(item)
 			if err != nil {
@@ -236,8 +271,24 @@ func (c *Compiler) Compile(node ast.INode) error {
 		}
 
 		c.emit(code.OpIndex)
+	case *ast.SliceExpression:
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+
+		for _, bound := range []ast.IExpression{node.Low, node.High} {
+			err = c.compileSliceBound(bound)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpSlice)
 	case *ast.FunctionLiteral:
 		c.enterScope()
+		loops, tries := c.loops, c.tries
+		c.loops, c.tries = nil, nil
 
 		if node.Name != "" {
 			c.symbolTable.DefineFunctionName(node.Name)
@@ -246,8 +297,16 @@ func (c *Compiler) Compile(node ast.INode) error {
 		for _, p := range node.Parameters {
 			c.symbolTable.Define(p.Value)
 		}
+		if node.Rest != nil {
+			c.symbolTable.Define(node.Rest.Value)
+		}
 
-		err := c.Compile(node.Body)
+		optionalCount, err := c.compileParameterDefaults(node)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(node.Body)
 		if err != nil {
 			return err
 		}
@@ -262,16 +321,22 @@ func (c *Compiler) Compile(node ast.INode) error {
 
 		freeSymbols := c.symbolTable.FreeSymbols
 		localsCount := c.symbolTable.numDefinitions
+		sourceMap := c.scopes[c.scopeIndex].sourceMap
 		instructions := c.leaveScope()
+		c.loops, c.tries = loops, tries
 
 		for _, sym := range freeSymbols {
-			c.loadSymbol(sym)
+			c.loadCell(sym)
 		}
 
 		compiledFn := &object.CompiledFunction{
-			Instructions:    instructions,
-			LocalsCount:     localsCount,
-			ParametersCount: len(node.Parameters),
+			Instructions:            instructions,
+			LocalsCount:             localsCount,
+			ParametersCount:         len(node.Parameters),
+			OptionalParametersCount: optionalCount,
+			Variadic:                node.Rest != nil,
+			Name:                    node.Name,
+			SourceMap:               sourceMap,
 		}
 		c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
 	case *ast.ReturnStatement:
@@ -279,6 +344,11 @@ func (c *Compiler) Compile(node ast.INode) error {
 		if err != nil {
 			return err
 		}
+
+		err = c.exitTries(0)
+		if err != nil {
+			return err
+		}
 		c.emit(code.OpReturnValue)
 	case *ast.CallExpression:
 		err := c.Compile(node.Func)
@@ -286,6 +356,17 @@ func (c *Compiler) Compile(node ast.INode) error {
 			return err
 		}
 
+		// OpCall holds the argument count in a single byte, longer or spread argument lists
+		// are passed as one array instead.
+		if hasSpread(node.Arguments) || len(node.Arguments) > math.MaxUint8 {
+			err := c.compileSpreadList(node.Arguments)
+			if err != nil {
+				return err
+			}
+			c.emit(code.OpCallSpread)
+			return nil
+		}
+
 		for _, arg := range node.Arguments {
 			err := c.Compile(arg)
 			if err != nil {
@@ -293,6 +374,536 @@ func (c *Compiler) Compile(node ast.INode) error {
 			}
 		}
 		c.emit(code.OpCall, len(node.Arguments))
+	case *ast.SpreadElement:
+		// Only reached through compileSpreadList, the array being built is on the stack.
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpExtendArray)
+	}
+	return nil
+}
+
+// compileDestructuring compiles `let <pattern> = <value>`. The value is kept in a hidden
+// binding and each element of the pattern is bound to the result of indexing it, nested
+// patterns take apart their part of the value the same way:
+//
+//	let [a, {b} = m] = <value>
+//	  <value>; set $0
+//	  get $0; OpConstant 0; OpIndex; set a
+//	  get $0; OpConstant 1; OpIndex; set $1
+//	  get $1; OpNull; OpEqual; OpJumpNotTruthy next; <m>; set $1
+//	  next: get $1; OpConstant "b"; OpIndex; set b
+func (c *Compiler) compileDestructuring(node *ast.LetStatement) error {
+	err := c.Compile(node.Value)
+	if err != nil {
+		return err
+	}
+
+	source, release := c.definePatternSymbol()
+	defer release()
+	c.storeSymbol(source)
+	return c.compilePattern(node.Pattern, source)
+}
+
+// definePatternSymbol defines a hidden binding for a value being destructured. Bindings in
+// use are not handed out again until released, a default value may destructure as well.
+func (c *Compiler) definePatternSymbol() (Symbol, func()) {
+	symbol := c.symbolTable.Define(fmt.Sprintf("%s%d", hiddenSymbolPrefix, c.patterns))
+	c.patterns++
+	return symbol, func() { c.patterns-- }
+}
+
+// compilePattern binds the elements of pattern to the parts of the value held by source.
+func (c *Compiler) compilePattern(pattern ast.IExpression, source Symbol) error {
+	previous := c.position
+	defer func() { c.position = previous }()
+
+	switch pattern := pattern.(type) {
+	case *ast.ArrayPattern:
+		for i, element := range pattern.Elements {
+			c.position = element.Target.Pos()
+			c.loadSymbol(source)
+			c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: int64(i)}))
+			c.emit(code.OpIndex)
+
+			err := c.compilePatternElement(element)
+			if err != nil {
+				return err
+			}
+		}
+
+		if pattern.Rest != nil {
+			c.position = pattern.Rest.Pos()
+			c.loadSymbol(source)
+			c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: int64(len(pattern.Elements))}))
+			c.emit(code.OpNull)
+			c.emit(code.OpSlice)
+			c.storeSymbol(c.symbolTable.Define(pattern.Rest.Value))
+		}
+	case *ast.MapPattern:
+		for _, element := range pattern.Elements {
+			c.position = element.Key.Pos()
+			c.loadSymbol(source)
+			c.emit(code.OpConstant, c.addConstant(&object.String{Value: element.Key.Value}))
+			c.emit(code.OpIndex)
+
+			err := c.compilePatternElement(element)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compilePatternElement binds the target of element to the value on top of the stack, or to
+// the default of the element when that value is null.
+func (c *Compiler) compilePatternElement(element *ast.PatternElement) error {
+	ident, isIdent := element.Target.(*ast.Identifier)
+	if isIdent && element.Default == nil {
+		c.storeSymbol(c.symbolTable.Define(ident.Value))
+		return nil
+	}
+
+	// The name is only defined once the default is compiled, which cannot refer to it.
+	value, release := c.definePatternSymbol()
+	defer release()
+	c.storeSymbol(value)
+
+	if element.Default != nil {
+		c.loadSymbol(value)
+		c.emit(code.OpNull)
+		c.emit(code.OpEqual)
+		jumpNotNullPos := c.emit(code.OpJumpNotTruthy, 999)
+
+		err := c.Compile(element.Default)
+		if err != nil {
+			return err
+		}
+		c.storeSymbol(value)
+		c.changeOperand(jumpNotNullPos, len(c.currentInstructions()))
+	}
+
+	if isIdent {
+		c.loadSymbol(value)
+		c.storeSymbol(c.symbolTable.Define(ident.Value))
+		return nil
+	}
+	return c.compilePattern(element.Target, value)
+}
+
+func hasSpread(items []ast.IExpression) bool {
+	for _, item := range items {
+		if _, ok := item.(*ast.SpreadElement); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// compileSpreadList builds one array out of items that may be spread. The plain items up to
+// the first spread start the array, then each spread and each run of plain items after one
+// is appended to it:
+//
+//	[1, ...a, 2, 3]
+//	  OpConstant 1; OpArray 1
+//	  <a>; OpExtendArray
+//	  OpConstant 2; OpConstant 3; OpArray 2; OpExtendArray
+func (c *Compiler) compileSpreadList(items []ast.IExpression) error {
+	pending := 0     // plain items on the stack that are not part of the array yet
+	started := false // whether the array is on the stack
+	flush := func() {
+		c.emit(code.OpArray, pending)
+		if started {
+			c.emit(code.OpExtendArray)
+		}
+		started = true
+		pending = 0
+	}
+
+	for _, item := range items {
+		_, isSpread := item.(*ast.SpreadElement)
+		if isSpread && (!started || pending > 0) {
+			flush()
+		}
+
+		err := c.Compile(item)
+		if err != nil {
+			return err
+		}
+		if !isSpread {
+			pending++
+		}
+	}
+
+	if !started || pending > 0 {
+		flush()
+	}
+	return nil
+}
+
+// compileLogicalExpression compiles `&&` and `||` so that the right operand only runs when
+// the left one does not decide the result. Both operators produce a boolean:
+//
+//	a && b                          a || b
+//	  <a>                             <a>
+//	  OpJumpNotTruthy false           OpJumpTruthy true
+//	  <b>                             <b>
+//	  OpJumpNotTruthy false           OpJumpTruthy true
+//	  OpTrue                          OpFalse
+//	  OpJump end                      OpJump end
+//	false: OpFalse                  true: OpTrue
+//	end:                            end:
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	jump, result, shortCircuit := code.OpJumpNotTruthy, code.OpTrue, code.OpFalse
+	if node.Operator == "||" {
+		jump, result, shortCircuit = code.OpJumpTruthy, code.OpFalse, code.OpTrue
+	}
+
+	var shortCircuitJumps []int
+	for _, operand := range []ast.IExpression{node.LeftValue, node.RightValue} {
+		err := c.Compile(operand)
+		if err != nil {
+			return err
+		}
+		shortCircuitJumps = append(shortCircuitJumps, c.emit(jump, 999))
+	}
+
+	c.emit(result)
+	jumpToEnd := c.emit(code.OpJump, 999)
+
+	shortCircuitPosition := len(c.currentInstructions())
+	for _, position := range shortCircuitJumps {
+		c.changeOperand(position, shortCircuitPosition)
+	}
+	c.emit(shortCircuit)
+
+	c.changeOperand(jumpToEnd, len(c.currentInstructions()))
+	return nil
+}
+
+// compoundOperators maps compound assignments to the arithmetic they perform.
+var compoundOperators = map[string]code.Opcode{
+	"+=": code.OpAdd,
+	"-=": code.OpSub,
+	"*=": code.OpMul,
+	"/=": code.OpDiv,
+	"%=": code.OpMod,
+}
+
+// compileAssignExpression stores the value in the binding and then loads it again, as the
+// assignment evaluates to the stored value. Builtins and the name a function refers to
+// itself by are read only.
+func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
+	var ident *ast.Identifier
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		ident = target
+	case *ast.IndexExpression:
+		return c.compileIndexAssignment(node, target)
+	default:
+		return fmt.Errorf("cannot assign to %s", node.Target)
+	}
+
+	symbol, ok := c.symbolTable.Resolve(ident.Value)
+	if !ok {
+		return fmt.Errorf("undefined variable %s", ident.Value)
+	}
+	switch symbol.Scope {
+	case BuiltinScope:
+		return fmt.Errorf("cannot assign to builtin %s", ident.Value)
+	case FunctionScope:
+		return fmt.Errorf("cannot assign to function %s inside its own body", ident.Value)
+	}
+
+	operator, compound := compoundOperators[node.Operator]
+	if compound {
+		c.loadSymbol(symbol)
+	}
+
+	err := c.Compile(node.Value)
+	if err != nil {
+		return err
+	}
+
+	if compound {
+		c.emit(operator)
+	}
+	c.storeSymbol(symbol)
+	c.loadSymbol(symbol)
+	return nil
+}
+
+// compileIndexAssignment compiles `left[index] = value`. OpSetIndex leaves the value on the
+// stack. A compound assignment evaluates left and index only once and duplicates them to
+// read the current element:
+//
+//	<left> <index> OpDuplicatePair OpIndex <value> <operator> OpSetIndex
+func (c *Compiler) compileIndexAssignment(node *ast.AssignExpression, target *ast.IndexExpression) error {
+	err := c.Compile(target.Left)
+	if err != nil {
+		return err
+	}
+
+	err = c.Compile(target.Index)
+	if err != nil {
+		return err
+	}
+
+	operator, compound := compoundOperators[node.Operator]
+	if compound {
+		c.emit(code.OpDuplicatePair)
+		c.emit(code.OpIndex)
+	}
+
+	err = c.Compile(node.Value)
+	if err != nil {
+		return err
+	}
+
+	if compound {
+		c.emit(operator)
+	}
+	c.emit(code.OpSetIndex)
+	return nil
+}
+
+// loopContext collects the jumps of the `break` and `continue` statements of a loop body,
+// their targets are only known once the whole loop has been compiled.
+type loopContext struct {
+	breaks    []int
+	continues []int
+	tries     int // number of try statements enclosing the loop
+}
+
+// compileWhileStatement lays out a while loop as:
+//
+//	start: <condition>
+//	       OpJumpNotTruthy end
+//	       <body>               continue: OpJump start, break: OpJump end
+//	       OpJump start
+//	end:
+func (c *Compiler) compileWhileStatement(node *ast.WhileStatement) error {
+	start := len(c.currentInstructions())
+
+	err := c.Compile(node.Condition)
+	if err != nil {
+		return err
+	}
+	exitJump := c.emit(code.OpJumpNotTruthy, 999)
+
+	loop, err := c.compileLoopBody(node.Body)
+	if err != nil {
+		return err
+	}
+
+	c.emit(code.OpJump, start)
+	end := len(c.currentInstructions())
+	c.changeOperand(exitJump, end)
+	c.patchLoopJumps(loop, start, end)
+	return nil
+}
+
+// compileForStatement lays out a for loop like a while loop whose `continue` jumps to the
+// post statement. Without a condition there is no exit jump and only `break` leaves it:
+//
+//	       <init>
+//	start: <condition>
+//	       OpJumpNotTruthy end
+//	       <body>
+//	post:  <post>
+//	       OpJump start
+//	end:
+func (c *Compiler) compileForStatement(node *ast.ForStatement) error {
+	if node.Init != nil {
+		err := c.Compile(node.Init)
+		if err != nil {
+			return err
+		}
+	}
+
+	start := len(c.currentInstructions())
+
+	exitJump := -1
+	if node.Condition != nil {
+		err := c.Compile(node.Condition)
+		if err != nil {
+			return err
+		}
+		exitJump = c.emit(code.OpJumpNotTruthy, 999)
+	}
+
+	loop, err := c.compileLoopBody(node.Body)
+	if err != nil {
+		return err
+	}
+
+	post := len(c.currentInstructions())
+	if node.Post != nil {
+		err := c.Compile(node.Post)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.emit(code.OpJump, start)
+	end := len(c.currentInstructions())
+	if exitJump != -1 {
+		c.changeOperand(exitJump, end)
+	}
+	c.patchLoopJumps(loop, post, end)
+	return nil
+}
+
+func (c *Compiler) compileLoopBody(body *ast.BlockStatement) (*loopContext, error) {
+	loop := &loopContext{tries: len(c.tries)}
+	c.loops = append(c.loops, loop)
+	err := c.Compile(body)
+	c.loops = c.loops[:len(c.loops)-1]
+	return loop, err
+}
+
+func (c *Compiler) patchLoopJumps(loop *loopContext, continueTarget, breakTarget int) {
+	for _, position := range loop.continues {
+		c.changeOperand(position, continueTarget)
+	}
+	for _, position := range loop.breaks {
+		c.changeOperand(position, breakTarget)
+	}
+}
+
+// tryContext describes a try statement for the nodes its handler guards, which need to run
+// the finally block on their way out of it.
+type tryContext struct {
+	finally *ast.BlockStatement // nil without a finally block
+	depth   int                 // number of try statements enclosing this one
+	loops   int                 // number of loops enclosing the try statement
+}
+
+// compileTryExpression sets up a handler for the try block with OpTry, which continues at the
+// catch block with the error value on the stack. With a finally block the catch block is
+// guarded as well, an error in it runs the finally block and is thrown again. The finally
+// block is compiled once for every way out:
+//
+//	        OpTry catch
+//	        <try block>; OpEndTry
+//	        <finally>; OpJump end
+//	catch:  set e
+//	        OpTry rethrow
+//	        <catch block>; OpEndTry
+//	        <finally>; OpJump end
+//	rethrow:
+//	        <finally>; OpThrow
+//	end:
+//
+// The parameter and the catch block are compiled in a block scope, so like in the evaluator
+// they do not touch the bindings of the same names outside of the catch block.
+//
+// A return, break or continue inside the try statement removes its handler and runs the
+// finally block before leaving, see exitTries.
+func (c *Compiler) compileTryExpression(node *ast.TryExpression) error {
+	try := &tryContext{finally: node.Finally, depth: len(c.tries), loops: len(c.loops)}
+
+	var jumpsToEnd []int
+	handler := c.emit(code.OpTry, 999)
+	err := c.compileGuardedBlock(try, node.Block)
+	if err != nil {
+		return err
+	}
+	err = c.compileFinally(try)
+	if err != nil {
+		return err
+	}
+	jumpsToEnd = append(jumpsToEnd, c.emit(code.OpJump, 999))
+	c.changeOperand(handler, len(c.currentInstructions()))
+
+	if node.Catch != nil {
+		exit := c.symbolTable.enterBlock()
+		if node.CatchParam != nil {
+			c.storeSymbol(c.symbolTable.Define(node.CatchParam.Value))
+		} else {
+			c.emit(code.OpPop)
+		}
+
+		if node.Finally == nil {
+			err := c.Compile(node.Catch)
+			exit()
+			if err != nil {
+				return err
+			}
+			c.keepBlockValue()
+		} else {
+			handler = c.emit(code.OpTry, 999)
+			err := c.compileGuardedBlock(try, node.Catch)
+			exit()
+			if err != nil {
+				return err
+			}
+			err = c.compileFinally(try)
+			if err != nil {
+				return err
+			}
+			jumpsToEnd = append(jumpsToEnd, c.emit(code.OpJump, 999))
+			c.changeOperand(handler, len(c.currentInstructions()))
+		}
+	}
+
+	if node.Finally != nil {
+		err := c.compileFinally(try)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpThrow)
+	}
+
+	end := len(c.currentInstructions())
+	for _, position := range jumpsToEnd {
+		c.changeOperand(position, end)
+	}
+	return nil
+}
+
+// compileGuardedBlock compiles a block guarded by the handler of try, leaving its value on
+// the stack, and removes the handler after it.
+func (c *Compiler) compileGuardedBlock(try *tryContext, block *ast.BlockStatement) error {
+	c.tries = append(c.tries, try)
+	err := c.Compile(block)
+	c.tries = c.tries[:len(c.tries)-1]
+	if err != nil {
+		return err
+	}
+
+	c.keepBlockValue()
+	c.emit(code.OpEndTry)
+	return nil
+}
+
+// compileFinally compiles the finally block of try, if there is one, as it appears in the
+// source: outside of the handler of try and of the loops inside the try statement.
+func (c *Compiler) compileFinally(try *tryContext) error {
+	if try.finally == nil {
+		return nil
+	}
+
+	tries, loops := c.tries, c.loops
+	c.tries = c.tries[:try.depth:try.depth]
+	c.loops = c.loops[:try.loops:try.loops]
+	err := c.Compile(try.finally)
+	c.tries, c.loops = tries, loops
+	return err
+}
+
+// exitTries leaves the try statements from the given depth on, innermost first, for a
+// return, break or continue jumping out of them.
+func (c *Compiler) exitTries(depth int) error {
+	for i := len(c.tries) - 1; i >= depth; i-- {
+		c.emit(code.OpEndTry)
+		err := c.compileFinally(c.tries[i])
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -301,6 +912,7 @@ func (c *Compiler) ByteCode() *ByteCode {
 	return &ByteCode{
 		Instructions: c.currentInstructions(),
 		Constants:    c.constants,
+		SourceMap:    c.scopes[c.scopeIndex].sourceMap,
 	}
 }
 
@@ -315,9 +927,44 @@ func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 	ins := code.MakeInstruction(op, operands...)
 	pos := c.addInstruction(ins)
 	c.setLastInstructions(op, pos)
+	c.addSourceMapping(pos)
 	return pos
 }
 
+// addSourceMapping maps the instruction at the given offset to the position of the node
+// being compiled. Consecutive instructions from the same position share one entry.
+func (c *Compiler) addSourceMapping(offset int) {
+	if !c.position.IsValid() {
+		return
+	}
+
+	sourceMap := c.scopes[c.scopeIndex].sourceMap
+	if len(sourceMap) > 0 && sourceMap[len(sourceMap)-1].Pos == c.position {
+		return
+	}
+	c.scopes[c.scopeIndex].sourceMap = append(sourceMap, code.SourceMapping{Offset: offset, Pos: c.position})
+}
+
+// sourcePosition returns the position runtime errors raised by the node's instructions
+// should point at. Operators are located at their operator token rather than at the start
+// of their left operand.
+func sourcePosition(node ast.INode) token.Position {
+	switch node := node.(type) {
+	case *ast.InfixExpression:
+		return node.Token.Pos
+	case *ast.CallExpression:
+		return node.Token.Pos
+	case *ast.IndexExpression:
+		return node.Token.Pos
+	case *ast.SliceExpression:
+		return node.Token.Pos
+	case *ast.AssignExpression:
+		return node.Token.Pos
+	default:
+		return node.Pos()
+	}
+}
+
 func (c *Compiler) addInstruction(ins []byte) int {
 	posNewInstruction := len(c.currentInstructions())
 	updatedInstructions := append(c.currentInstructions(), ins...)
@@ -342,6 +989,7 @@ func (c *Compiler) removeLastPop() {
 
 	c.scopes[c.scopeIndex].instructions = newIns
 	c.scopes[c.scopeIndex].lastInstruction = prev
+	c.scopes[c.scopeIndex].sourceMap = c.scopes[c.scopeIndex].sourceMap.Truncate(last.Position)
 }
 
 // replaceInstruction replaces the instruction at the specified position in the Compiler's instruction list with the new instruction.
@@ -390,6 +1038,281 @@ func (c *Compiler) leaveScope() code.Instructions {
 	return ins
 }
 
+// compileParameterDefaults emits the prologue of a function that assigns their default value
+// to the parameters the caller left out and returns the number of such parameters:
+//
+//	OpHasArgument i OpJumpTruthy next <default> OpSetLocal i
+//	next: ...
+//
+// A default value is compiled while the parameter itself and the ones after it are hidden,
+// so like in the evaluator it can only refer to the parameters before it.
+func (c *Compiler) compileParameterDefaults(node *ast.FunctionLiteral) (int, error) {
+	var hidden []string
+	for _, p := range node.Parameters {
+		hidden = append(hidden, p.Value)
+	}
+	if node.Rest != nil {
+		hidden = append(hidden, node.Rest.Value)
+	}
+
+	optionalCount := 0
+	for i, p := range node.Parameters {
+		if i >= len(node.Defaults) || node.Defaults[i] == nil {
+			continue
+		}
+		optionalCount++
+
+		symbol, _ := c.symbolTable.Resolve(p.Value)
+		c.emit(code.OpHasArgument, symbol.Index)
+		jumpTruthyPosition := c.emit(code.OpJumpTruthy, 999)
+
+		restore := c.symbolTable.hide(hidden[i:])
+		err := c.Compile(node.Defaults[i])
+		restore()
+		if err != nil {
+			return 0, err
+		}
+
+		c.emit(code.OpSetLocal, symbol.Index)
+		c.changeOperand(jumpTruthyPosition, len(c.currentInstructions()))
+	}
+	return optionalCount, nil
+}
+
+// compileIfExpression compiles an if expression and its else-if clauses into one flat
+// sequence. A false condition jumps to the next condition, every consequence jumps to the end:
+//
+//	<condition> OpJumpNotTruthy next <consequence> OpJump end
+//	next: <condition> OpJumpNotTruthy next <consequence> OpJump end
+//	next: <alternative or OpNull>
+//	end:
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	branches := append([]*ast.ElseIf{{Condition: node.Condition, Consequence: node.Consequence}}, node.ElseIfs...)
+
+	var jumpsToEnd []int
+	for _, branch := range branches {
+		err := c.Compile(branch.Condition)
+		if err != nil {
+			return err
+		}
+
+		jumpNotTruthyPosition := c.emit(code.OpJumpNotTruthy, 999)
+
+		err = c.Compile(branch.Consequence)
+		if err != nil {
+			return err
+		}
+
+		c.keepBlockValue()
+
+		jumpsToEnd = append(jumpsToEnd, c.emit(code.OpJump, 999))
+		c.changeOperand(jumpNotTruthyPosition, len(c.currentInstructions()))
+	}
+
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+	} else {
+		err := c.Compile(node.Alternative)
+		if err != nil {
+			return err
+		}
+
+		c.keepBlockValue()
+	}
+
+	afterAlternativePosition := len(c.currentInstructions())
+	for _, jumpPosition := range jumpsToEnd {
+		c.changeOperand(jumpPosition, afterAlternativePosition)
+	}
+	return nil
+}
+
+// compileMatchExpression compiles a match into a chain of arms. The subject is kept in a
+// hidden binding, every test of an arm jumps to the next arm when it fails and a matching
+// arm jumps past the rest once its body has left its value on the stack:
+//
+//	<subject>; set $0
+//	  <tests of arm 1>; <guard>; OpJumpNotTruthy arm2; <body 1>; OpJump end
+//	arm2:
+//	  <tests of arm 2>; ...; OpJump end
+//	arm3:
+//	  OpNull
+//	end:
+func (c *Compiler) compileMatchExpression(node *ast.MatchExpression) error {
+	err := c.Compile(node.Subject)
+	if err != nil {
+		return err
+	}
+
+	subject, release := c.definePatternSymbol()
+	defer release()
+	c.storeSymbol(subject)
+
+	var jumpsToEnd []int
+	for _, arm := range node.Arms {
+		var jumpsToNextArm []int
+		exit := c.symbolTable.enterBlock()
+		err := c.compileMatchArm(arm, subject, &jumpsToNextArm)
+		exit()
+		if err != nil {
+			return err
+		}
+
+		jumpsToEnd = append(jumpsToEnd, c.emit(code.OpJump, 999))
+		for _, jumpPosition := range jumpsToNextArm {
+			c.changeOperand(jumpPosition, len(c.currentInstructions()))
+		}
+	}
+
+	c.emit(code.OpNull)
+
+	afterMatchPosition := len(c.currentInstructions())
+	for _, jumpPosition := range jumpsToEnd {
+		c.changeOperand(jumpPosition, afterMatchPosition)
+	}
+	return nil
+}
+
+// compileMatchArm compiles the tests, the guard and the body of arm. It is compiled in a block
+// scope, so like in the evaluator the names the pattern binds and the ones the body defines
+// get slots of their own, and a binding outside the match is left alone whether the arm
+// matches or not.
+func (c *Compiler) compileMatchArm(arm *ast.MatchArm, subject Symbol, failJumps *[]int) error {
+	err := c.compileMatchPattern(arm.Pattern, subject, failJumps)
+	if err != nil {
+		return err
+	}
+
+	if arm.Guard != nil {
+		err := c.Compile(arm.Guard)
+		if err != nil {
+			return err
+		}
+		*failJumps = append(*failJumps, c.emit(code.OpJumpNotTruthy, 999))
+	}
+
+	err = c.Compile(arm.Body)
+	if err != nil {
+		return err
+	}
+	if _, ok := arm.Body.(*ast.BlockStatement); ok {
+		c.keepBlockValue()
+	}
+	return nil
+}
+
+// compileMatchPattern tests the value held by source against pattern and binds the names
+// the pattern introduces. The positions of the jumps taken when the test fails are added to
+// failJumps.
+func (c *Compiler) compileMatchPattern(pattern ast.IExpression, source Symbol, failJumps *[]int) error {
+	if ast.IsWildcard(pattern) {
+		return nil
+	}
+
+	switch pattern := pattern.(type) {
+	case *ast.ArrayPattern:
+		c.loadSymbol(source)
+		c.emit(code.OpMatchArray, len(pattern.Elements), boolOperand(pattern.Rest != nil))
+		*failJumps = append(*failJumps, c.emit(code.OpJumpNotTruthy, 999))
+
+		for i, element := range pattern.Elements {
+			if ast.IsWildcard(element.Target) {
+				continue
+			}
+			c.loadSymbol(source)
+			c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: int64(i)}))
+			c.emit(code.OpIndex)
+
+			err := c.compileMatchElement(element.Target, failJumps)
+			if err != nil {
+				return err
+			}
+		}
+
+		if pattern.Rest != nil && !ast.IsWildcard(pattern.Rest) {
+			c.loadSymbol(source)
+			c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: int64(len(pattern.Elements))}))
+			c.emit(code.OpNull)
+			c.emit(code.OpSlice)
+			c.storeSymbol(c.symbolTable.Define(pattern.Rest.Value))
+		}
+	case *ast.MapPattern:
+		c.loadSymbol(source)
+		for _, element := range pattern.Elements {
+			c.emit(code.OpConstant, c.addConstant(&object.String{Value: element.Key.Value}))
+		}
+		c.emit(code.OpMatchMap, len(pattern.Elements))
+		*failJumps = append(*failJumps, c.emit(code.OpJumpNotTruthy, 999))
+
+		for _, element := range pattern.Elements {
+			if ast.IsWildcard(element.Target) {
+				continue
+			}
+			c.loadSymbol(source)
+			c.emit(code.OpConstant, c.addConstant(&object.String{Value: element.Key.Value}))
+			c.emit(code.OpIndex)
+
+			err := c.compileMatchElement(element.Target, failJumps)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		c.loadSymbol(source)
+		return c.compileMatchElement(pattern, failJumps)
+	}
+	return nil
+}
+
+// compileMatchElement tests the value on top of the stack against pattern, which is not the
+// wildcard, binding it to the pattern if it is an identifier.
+func (c *Compiler) compileMatchElement(pattern ast.IExpression, failJumps *[]int) error {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		c.storeSymbol(c.symbolTable.Define(pattern.Value))
+	case *ast.ArrayPattern, *ast.MapPattern:
+		value, release := c.definePatternSymbol()
+		defer release()
+		c.storeSymbol(value)
+		return c.compileMatchPattern(pattern, value, failJumps)
+	default:
+		err := c.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpEqual)
+		*failJumps = append(*failJumps, c.emit(code.OpJumpNotTruthy, 999))
+	}
+	return nil
+}
+
+func boolOperand(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// compileSliceBound compiles a bound of a slice expression, a left out bound is passed to
+// OpSlice as null.
+func (c *Compiler) compileSliceBound(bound ast.IExpression) error {
+	if bound == nil {
+		c.emit(code.OpNull)
+		return nil
+	}
+	return c.Compile(bound)
+}
+
+// keepBlockValue leaves the value of a just compiled if branch on the stack. A block whose
+// last statement is not an expression, e.g. a let binding or a loop, evaluates to null.
+func (c *Compiler) keepBlockValue() {
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+}
+
 func (c *Compiler) replaceLastPopWithReturn() {
 	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
 	c.replaceInstruction(lastPos, code.MakeInstruction(code.OpReturnValue))
@@ -411,8 +1334,33 @@ func (c *Compiler) loadSymbol(s Symbol) {
 	}
 }
 
+func (c *Compiler) storeSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpSetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpSetFree, s.Index)
+	}
+}
+
+// loadCell pushes a variable captured by a closure being created. Locals and free variables
+// are shared through their cell rather than copied, the enclosing closure is a plain value.
+func (c *Compiler) loadCell(s Symbol) {
+	switch s.Scope {
+	case LocalScope:
+		c.emit(code.OpGetLocalCell, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFreeCell, s.Index)
+	default:
+		c.loadSymbol(s)
+	}
+}
+
 type CompilationScope struct {
 	instructions        code.Instructions
 	lastInstruction     EmittedInstructions
 	previousInstruction EmittedInstructions
+	sourceMap           code.SourceMap
 }
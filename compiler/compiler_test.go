@@ -16,6 +16,781 @@ type compilerTestCase struct {
 	expectedInstructions []code.Instructions
 }
 
+func TestCompileTryExpression(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			input:             "try { 1 } catch (e) { e }",
+			expectedConstants: []any{1},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpTry, 10),      // 0000
+				code.MakeInstruction(code.OpConstant, 0),  // 0003
+				code.MakeInstruction(code.OpEndTry),       // 0006
+				code.MakeInstruction(code.OpJump, 16),     // 0007
+				code.MakeInstruction(code.OpSetGlobal, 0), // 0010
+				code.MakeInstruction(code.OpGetGlobal, 0), // 0013
+				code.MakeInstruction(code.OpPop),          // 0016
+			},
+		},
+		{
+			input:             "try { throw 1 } finally { 2 }",
+			expectedConstants: []any{1, 2, 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpTry, 16),     // 0000
+				code.MakeInstruction(code.OpConstant, 0), // 0003
+				code.MakeInstruction(code.OpThrow),       // 0006
+				code.MakeInstruction(code.OpNull),        // 0007
+				code.MakeInstruction(code.OpEndTry),      // 0008
+				code.MakeInstruction(code.OpConstant, 1), // 0009
+				code.MakeInstruction(code.OpPop),         // 0012
+				code.MakeInstruction(code.OpJump, 21),    // 0013
+				code.MakeInstruction(code.OpConstant, 2), // 0016
+				code.MakeInstruction(code.OpPop),         // 0019
+				code.MakeInstruction(code.OpThrow),       // 0020
+				code.MakeInstruction(code.OpPop),         // 0021
+			},
+		},
+		{
+			// A return leaves the handler and runs the finally block first.
+			input: "fn() { try { return 1 } finally { 2 } }",
+			expectedConstants: []any{
+				1, 2, 2, 2,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpTry, 21),     // 0000
+					code.MakeInstruction(code.OpConstant, 0), // 0003
+					code.MakeInstruction(code.OpEndTry),      // 0006
+					code.MakeInstruction(code.OpConstant, 1), // 0007
+					code.MakeInstruction(code.OpPop),         // 0010
+					code.MakeInstruction(code.OpReturnValue), // 0011
+					code.MakeInstruction(code.OpNull),        // 0012
+					code.MakeInstruction(code.OpEndTry),      // 0013
+					code.MakeInstruction(code.OpConstant, 2), // 0014
+					code.MakeInstruction(code.OpPop),         // 0017
+					code.MakeInstruction(code.OpJump, 26),    // 0018
+					code.MakeInstruction(code.OpConstant, 3), // 0021
+					code.MakeInstruction(code.OpPop),         // 0024
+					code.MakeInstruction(code.OpThrow),       // 0025
+					code.MakeInstruction(code.OpReturnValue), // 0026
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 4, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			// A break only leaves the try statements inside the loop.
+			input:             "try { while (true) { try { break; } catch { 1 } } } catch { 2 }",
+			expectedConstants: []any{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpTry, 32),           // 0000
+				code.MakeInstruction(code.OpTrue),              // 0003
+				code.MakeInstruction(code.OpJumpNotTruthy, 27), // 0004
+				code.MakeInstruction(code.OpTry, 19),           // 0007
+				code.MakeInstruction(code.OpEndTry),            // 0010
+				code.MakeInstruction(code.OpJump, 27),          // 0011
+				code.MakeInstruction(code.OpNull),              // 0014
+				code.MakeInstruction(code.OpEndTry),            // 0015
+				code.MakeInstruction(code.OpJump, 23),          // 0016
+				code.MakeInstruction(code.OpPop),               // 0019
+				code.MakeInstruction(code.OpConstant, 0),       // 0020
+				code.MakeInstruction(code.OpPop),               // 0023
+				code.MakeInstruction(code.OpJump, 3),           // 0024
+				code.MakeInstruction(code.OpNull),              // 0027
+				code.MakeInstruction(code.OpEndTry),            // 0028
+				code.MakeInstruction(code.OpJump, 36),          // 0029
+				code.MakeInstruction(code.OpPop),               // 0032
+				code.MakeInstruction(code.OpConstant, 1),       // 0033
+				code.MakeInstruction(code.OpPop),               // 0036
+			},
+		},
+	}
+	runCompilerTests(t, testCases)
+}
+
+func TestCompileMatchExpression(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			input:             "match ([1]) { [x] if x => x, _ => 0 }",
+			expectedConstants: []any{1, 0, 0},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),       // 0000
+				code.MakeInstruction(code.OpArray, 1),          // 0003
+				code.MakeInstruction(code.OpSetGlobal, 0),      // 0006
+				code.MakeInstruction(code.OpGetGlobal, 0),      // 0009
+				code.MakeInstruction(code.OpMatchArray, 1, 0),  // 0012
+				code.MakeInstruction(code.OpJumpNotTruthy, 41), // 0016
+				code.MakeInstruction(code.OpGetGlobal, 0),      // 0019
+				code.MakeInstruction(code.OpConstant, 1),       // 0022
+				code.MakeInstruction(code.OpIndex),             // 0025
+				code.MakeInstruction(code.OpSetGlobal, 1),      // 0026
+				code.MakeInstruction(code.OpGetGlobal, 1),      // 0029
+				code.MakeInstruction(code.OpJumpNotTruthy, 41), // 0032
+				code.MakeInstruction(code.OpGetGlobal, 1),      // 0035
+				code.MakeInstruction(code.OpJump, 48),          // 0038
+				code.MakeInstruction(code.OpConstant, 2),       // 0041
+				code.MakeInstruction(code.OpJump, 48),          // 0044
+				code.MakeInstruction(code.OpNull),              // 0047
+				code.MakeInstruction(code.OpPop),               // 0048
+			},
+		},
+		{
+			input:             `let m = {}; match (m) { {"k": "v", rest: _} => { 1 } }`,
+			expectedConstants: []any{"k", "rest", "k", "v", 1},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpMap, 0),            // 0000
+				code.MakeInstruction(code.OpSetGlobal, 0),      // 0003
+				code.MakeInstruction(code.OpGetGlobal, 0),      // 0006
+				code.MakeInstruction(code.OpSetGlobal, 1),      // 0009
+				code.MakeInstruction(code.OpGetGlobal, 1),      // 0012
+				code.MakeInstruction(code.OpConstant, 0),       // 0015
+				code.MakeInstruction(code.OpConstant, 1),       // 0018
+				code.MakeInstruction(code.OpMatchMap, 2),       // 0021
+				code.MakeInstruction(code.OpJumpNotTruthy, 47), // 0024
+				code.MakeInstruction(code.OpGetGlobal, 1),      // 0027
+				code.MakeInstruction(code.OpConstant, 2),       // 0030
+				code.MakeInstruction(code.OpIndex),             // 0033
+				code.MakeInstruction(code.OpConstant, 3),       // 0034
+				code.MakeInstruction(code.OpEqual),             // 0037
+				code.MakeInstruction(code.OpJumpNotTruthy, 47), // 0038
+				code.MakeInstruction(code.OpConstant, 4),       // 0041
+				code.MakeInstruction(code.OpJump, 48),          // 0044
+				code.MakeInstruction(code.OpNull),              // 0047
+				code.MakeInstruction(code.OpPop),               // 0048
+			},
+		},
+	}
+	runCompilerTests(t, testCases)
+}
+
+func TestCompileDestructuring(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			input:             "let [a, b = 1] = [2];",
+			expectedConstants: []any{2, 0, 1, 1},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),       // 0000
+				code.MakeInstruction(code.OpArray, 1),          // 0003
+				code.MakeInstruction(code.OpSetGlobal, 0),      // 0006
+				code.MakeInstruction(code.OpGetGlobal, 0),      // 0009
+				code.MakeInstruction(code.OpConstant, 1),       // 0012
+				code.MakeInstruction(code.OpIndex),             // 0015
+				code.MakeInstruction(code.OpSetGlobal, 1),      // 0016
+				code.MakeInstruction(code.OpGetGlobal, 0),      // 0019
+				code.MakeInstruction(code.OpConstant, 2),       // 0022
+				code.MakeInstruction(code.OpIndex),             // 0025
+				code.MakeInstruction(code.OpSetGlobal, 2),      // 0026
+				code.MakeInstruction(code.OpGetGlobal, 2),      // 0029
+				code.MakeInstruction(code.OpNull),              // 0032
+				code.MakeInstruction(code.OpEqual),             // 0033
+				code.MakeInstruction(code.OpJumpNotTruthy, 43), // 0034
+				code.MakeInstruction(code.OpConstant, 3),       // 0037
+				code.MakeInstruction(code.OpSetGlobal, 2),      // 0040
+				code.MakeInstruction(code.OpGetGlobal, 2),      // 0043
+				code.MakeInstruction(code.OpSetGlobal, 3),      // 0046
+			},
+		},
+		{
+			input:             `let {k: [x]} = {"k": [1]};`,
+			expectedConstants: []any{"k", 1, "k", 0},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpArray, 1),
+				code.MakeInstruction(code.OpMap, 2),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpIndex),
+				code.MakeInstruction(code.OpSetGlobal, 1),
+				code.MakeInstruction(code.OpGetGlobal, 1),
+				code.MakeInstruction(code.OpConstant, 3),
+				code.MakeInstruction(code.OpIndex),
+				code.MakeInstruction(code.OpSetGlobal, 2),
+			},
+		},
+		{
+			input: "fn(p) { let [a, ...r] = p; a }",
+			expectedConstants: []any{
+				0,
+				1,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpSetLocal, 1),
+					code.MakeInstruction(code.OpGetLocal, 1),
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpIndex),
+					code.MakeInstruction(code.OpSetLocal, 2),
+					code.MakeInstruction(code.OpGetLocal, 1),
+					code.MakeInstruction(code.OpConstant, 1),
+					code.MakeInstruction(code.OpNull),
+					code.MakeInstruction(code.OpSlice),
+					code.MakeInstruction(code.OpSetLocal, 3),
+					code.MakeInstruction(code.OpGetLocal, 2),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 2, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, testCases)
+}
+
+func TestCompileSpread(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			input:             "let a = [1]; [0, ...a, 2, 3]",
+			expectedConstants: []any{1, 0, 2, 3},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpArray, 1),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpArray, 1),
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				code.MakeInstruction(code.OpExtendArray),
+				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpConstant, 3),
+				code.MakeInstruction(code.OpArray, 2),
+				code.MakeInstruction(code.OpExtendArray),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input:             "let a = [1]; len(...a, ...a)",
+			expectedConstants: []any{1},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpArray, 1),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpGetBuiltin, 0),
+				code.MakeInstruction(code.OpArray, 0),
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				code.MakeInstruction(code.OpExtendArray),
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				code.MakeInstruction(code.OpExtendArray),
+				code.MakeInstruction(code.OpCallSpread),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, testCases)
+}
+
+func TestCompileParameterDefaults(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			input: "fn(a, b = a + 1, ...rest) { b }",
+			expectedConstants: []any{
+				1,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpHasArgument, 1), // 0000
+					code.MakeInstruction(code.OpJumpTruthy, 13), // 0002
+					code.MakeInstruction(code.OpGetLocal, 0),    // 0005
+					code.MakeInstruction(code.OpConstant, 0),    // 0007
+					code.MakeInstruction(code.OpAdd),            // 0010
+					code.MakeInstruction(code.OpSetLocal, 1),    // 0011
+					code.MakeInstruction(code.OpGetLocal, 1),    // 0013
+					code.MakeInstruction(code.OpReturnValue),    // 0015
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 1, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			// A default value cannot see its own parameter or the ones after it.
+			input: "let c = 5; fn(a = c, c = 1) { a }",
+			expectedConstants: []any{
+				5,
+				1,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpHasArgument, 0), // 0000
+					code.MakeInstruction(code.OpJumpTruthy, 10), // 0002
+					code.MakeInstruction(code.OpGetGlobal, 0),   // 0005
+					code.MakeInstruction(code.OpSetLocal, 0),    // 0008
+					code.MakeInstruction(code.OpHasArgument, 1), // 0010
+					code.MakeInstruction(code.OpJumpTruthy, 20), // 0012
+					code.MakeInstruction(code.OpConstant, 1),    // 0015
+					code.MakeInstruction(code.OpSetLocal, 1),    // 0018
+					code.MakeInstruction(code.OpGetLocal, 0),    // 0020
+					code.MakeInstruction(code.OpReturnValue),    // 0022
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpClosure, 2, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, testCases)
+
+	comp := NewCompiler()
+	err := comp.Compile(parse("fn(a, b = 2, ...rest) { a }"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	fn := comp.ByteCode().Constants[1].(*object.CompiledFunction)
+	if fn.ParametersCount != 2 || fn.OptionalParametersCount != 1 || !fn.Variadic || fn.LocalsCount != 3 {
+		t.Errorf("fn = (params=%d, optional=%d, variadic=%t, locals=%d), want = (2, 1, true, 3)",
+			fn.ParametersCount, fn.OptionalParametersCount, fn.Variadic, fn.LocalsCount)
+	}
+}
+
+func TestCompileElseIfChains(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			input:             "if (true) { 10 } else if (false) { 20 } else { 30 }; 40;",
+			expectedConstants: []any{10, 20, 30, 40},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpTrue),              // 0000
+				code.MakeInstruction(code.OpJumpNotTruthy, 10), // 0001
+				code.MakeInstruction(code.OpConstant, 0),       // 0004
+				code.MakeInstruction(code.OpJump, 23),          // 0007
+				code.MakeInstruction(code.OpFalse),             // 0010
+				code.MakeInstruction(code.OpJumpNotTruthy, 20), // 0011
+				code.MakeInstruction(code.OpConstant, 1),       // 0014
+				code.MakeInstruction(code.OpJump, 23),          // 0017
+				code.MakeInstruction(code.OpConstant, 2),       // 0020
+				code.MakeInstruction(code.OpPop),               // 0023
+				code.MakeInstruction(code.OpConstant, 3),       // 0024
+				code.MakeInstruction(code.OpPop),               // 0027
+			},
+		},
+		{
+			input:             "if (false) { 10 } else if (true) { 20 }",
+			expectedConstants: []any{10, 20},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpFalse),             // 0000
+				code.MakeInstruction(code.OpJumpNotTruthy, 10), // 0001
+				code.MakeInstruction(code.OpConstant, 0),       // 0004
+				code.MakeInstruction(code.OpJump, 21),          // 0007
+				code.MakeInstruction(code.OpTrue),              // 0010
+				code.MakeInstruction(code.OpJumpNotTruthy, 20), // 0011
+				code.MakeInstruction(code.OpConstant, 1),       // 0014
+				code.MakeInstruction(code.OpJump, 21),          // 0017
+				code.MakeInstruction(code.OpNull),              // 0020
+				code.MakeInstruction(code.OpPop),               // 0021
+			},
+		},
+		{
+			input:             "null",
+			expectedConstants: []any{},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpNull),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, testCases)
+}
+
+func TestCompileSliceExpressions(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			input:             `"abc"[1:2]`,
+			expectedConstants: []any{"abc", 1, 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpSlice),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input:             "[1][:1]",
+			expectedConstants: []any{1, 1},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpArray, 1),
+				code.MakeInstruction(code.OpNull),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpSlice),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, testCases)
+}
+
+func TestCompileInterpolatedStrings(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			input:             `let x = 1; "a ${x} b ${x + 2}"`,
+			expectedConstants: []any{1, "a ", " b ", 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				code.MakeInstruction(code.OpConstant, 3),
+				code.MakeInstruction(code.OpAdd),
+				code.MakeInstruction(code.OpConcat, 4),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input:             `"${true}"`,
+			expectedConstants: []any{},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpTrue),
+				code.MakeInstruction(code.OpConcat, 1),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, testCases)
+}
+
+func TestCompileIndexAssignment(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			input:             "let a = [1]; a[0] = 2;",
+			expectedConstants: []any{1, 0, 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpArray, 1),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpConstant, 2),
+				code.MakeInstruction(code.OpSetIndex),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input:             `let m = {}; m["k"] += 1;`,
+			expectedConstants: []any{"k", 1},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpMap, 0),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpDuplicatePair),
+				code.MakeInstruction(code.OpIndex),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpAdd),
+				code.MakeInstruction(code.OpSetIndex),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, testCases)
+}
+
+func TestCompileAssignExpressions(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			input:             "let x = 1; x = 2;",
+			expectedConstants: []any{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpSetGlobal, 0),
+				code.MakeInstruction(code.OpGetGlobal, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: "fn(a) { a += 1 }",
+			expectedConstants: []any{
+				1,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpAdd),
+					code.MakeInstruction(code.OpSetLocal, 0),
+					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 1, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input: "fn(a) { fn() { a = 2 } }",
+			expectedConstants: []any{
+				2,
+				[]code.Instructions{
+					code.MakeInstruction(code.OpConstant, 0),
+					code.MakeInstruction(code.OpSetFree, 0),
+					code.MakeInstruction(code.OpGetFree, 0),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.MakeInstruction(code.OpGetLocalCell, 0),
+					code.MakeInstruction(code.OpClosure, 1, 1),
+					code.MakeInstruction(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpClosure, 2, 0),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, testCases)
+
+	errorCases := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 1", "undefined variable x"},
+		{"fn() { y -= 1 }", "undefined variable y"},
+		{"len = 1", "cannot assign to builtin len"},
+		{"let f = fn() { f = 1 }", "cannot assign to function f inside its own body"},
+	}
+
+	for _, tc := range errorCases {
+		compiler := NewCompiler()
+		err := compiler.Compile(parse(tc.input))
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("%s: err = %v, want = %q", tc.input, err, tc.expected)
+		}
+	}
+}
+
+func TestCompileLoops(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			input:             "while (true) { 1; }",
+			expectedConstants: []any{1},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpTrue),              // 0000
+				code.MakeInstruction(code.OpJumpNotTruthy, 11), // 0001
+				code.MakeInstruction(code.OpConstant, 0),       // 0004
+				code.MakeInstruction(code.OpPop),               // 0007
+				code.MakeInstruction(code.OpJump, 0),           // 0008
+			},
+		},
+		{
+			input:             "while (true) { break; continue; }",
+			expectedConstants: []any{},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpTrue),              // 0000
+				code.MakeInstruction(code.OpJumpNotTruthy, 13), // 0001
+				code.MakeInstruction(code.OpJump, 13),          // 0004
+				code.MakeInstruction(code.OpJump, 0),           // 0007
+				code.MakeInstruction(code.OpJump, 0),           // 0010
+			},
+		},
+		{
+			input:             "for (let i = 0; i < 2; let i = i + 1) { continue; }",
+			expectedConstants: []any{0, 2, 1},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),       // 0000
+				code.MakeInstruction(code.OpSetGlobal, 0),      // 0003
+				code.MakeInstruction(code.OpGetGlobal, 0),      // 0006
+				code.MakeInstruction(code.OpConstant, 1),       // 0009
+				code.MakeInstruction(code.OpLessThan),          // 0012
+				code.MakeInstruction(code.OpJumpNotTruthy, 32), // 0013
+				code.MakeInstruction(code.OpJump, 19),          // 0016
+				code.MakeInstruction(code.OpGetGlobal, 0),      // 0019
+				code.MakeInstruction(code.OpConstant, 2),       // 0022
+				code.MakeInstruction(code.OpAdd),               // 0025
+				code.MakeInstruction(code.OpSetGlobal, 0),      // 0026
+				code.MakeInstruction(code.OpJump, 6),           // 0029
+			},
+		},
+		{
+			input:             "for (;;) { break; }",
+			expectedConstants: []any{},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpJump, 6), // 0000
+				code.MakeInstruction(code.OpJump, 0), // 0003
+			},
+		},
+		{
+			input:             "if (true) { let x = 1; }",
+			expectedConstants: []any{1},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpTrue),              // 0000
+				code.MakeInstruction(code.OpJumpNotTruthy, 14), // 0001
+				code.MakeInstruction(code.OpConstant, 0),       // 0004
+				code.MakeInstruction(code.OpSetGlobal, 0),      // 0007
+				code.MakeInstruction(code.OpNull),              // 0010
+				code.MakeInstruction(code.OpJump, 15),          // 0011
+				code.MakeInstruction(code.OpNull),              // 0014
+				code.MakeInstruction(code.OpPop),               // 0015
+			},
+		},
+	}
+	runCompilerTests(t, testCases)
+}
+
+func TestCompileComparisonOperators(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			input:             "1 <= 2",
+			expectedConstants: []any{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpLessThanOrEqual),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input:             "1 >= 2",
+			expectedConstants: []any{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpGreaterThanOrEqual),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, testCases)
+}
+
+func TestCompileLogicalOperators(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			input:             "1 && 2",
+			expectedConstants: []any{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.MakeInstruction(code.OpConstant, 0),
+				// 0003
+				code.MakeInstruction(code.OpJumpNotTruthy, 16),
+				// 0006
+				code.MakeInstruction(code.OpConstant, 1),
+				// 0009
+				code.MakeInstruction(code.OpJumpNotTruthy, 16),
+				// 0012
+				code.MakeInstruction(code.OpTrue),
+				// 0013
+				code.MakeInstruction(code.OpJump, 17),
+				// 0016
+				code.MakeInstruction(code.OpFalse),
+				// 0017
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input:             "1 || 2",
+			expectedConstants: []any{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.MakeInstruction(code.OpConstant, 0),
+				// 0003
+				code.MakeInstruction(code.OpJumpTruthy, 16),
+				// 0006
+				code.MakeInstruction(code.OpConstant, 1),
+				// 0009
+				code.MakeInstruction(code.OpJumpTruthy, 16),
+				// 0012
+				code.MakeInstruction(code.OpFalse),
+				// 0013
+				code.MakeInstruction(code.OpJump, 17),
+				// 0016
+				code.MakeInstruction(code.OpTrue),
+				// 0017
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, testCases)
+}
+
+func TestCompileFloatArithmetic(t *testing.T) {
+	testCases := []compilerTestCase{
+		{
+			input:             "1.5 * 2",
+			expectedConstants: []any{1.5, 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpMul),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+		{
+			input:             "-2.5e3",
+			expectedConstants: []any{2500.0},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpMinus),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, testCases)
+}
+
+func TestCompileSourceMap(t *testing.T) {
+	input := "let x = 1 + 2;\nif (x > 1) { x } else { -x }"
+
+	expected := []struct {
+		offset int
+		pos    string
+	}{
+		{0, "1:9"},   // OpConstant 0
+		{3, "1:13"},  // OpConstant 1
+		{6, "1:11"},  // OpAdd
+		{7, "1:1"},   // OpSetGlobal 0
+		{10, "2:5"},  // OpGetGlobal 0
+		{13, "2:9"},  // OpConstant 2
+		{16, "2:7"},  // OpGreaterThan
+		{17, "2:1"},  // OpJumpNotTruthy 26
+		{20, "2:14"}, // OpGetGlobal 0
+		{23, "2:1"},  // OpJump 30
+		{26, "2:26"}, // OpGetGlobal 0
+		{29, "2:25"}, // OpMinus
+		{30, "2:1"},  // OpPop
+	}
+
+	compiler := NewCompiler()
+	err := compiler.Compile(parse(input))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	sourceMap := compiler.ByteCode().SourceMap
+	if len(sourceMap) != len(expected) {
+		t.Fatalf("len(sourceMap) = %d, want = %d. got = %v", len(sourceMap), len(expected), sourceMap)
+	}
+	for i, want := range expected {
+		if sourceMap[i].Offset != want.offset || sourceMap[i].Pos.String() != want.pos {
+			t.Errorf("sourceMap[%d] = {%d %s}, want = {%d %s}", i, sourceMap[i].Offset, sourceMap[i].Pos, want.offset, want.pos)
+		}
+	}
+}
+
+func TestCompileFunctionNameAndSourceMap(t *testing.T) {
+	compiler := NewCompiler()
+	err := compiler.Compile(parse("let double = fn(x) {\n  x * 2\n};"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	fn, ok := compiler.ByteCode().Constants[1].(*object.CompiledFunction)
+	if !ok {
+		t.Fatalf("constant 1 is not a function: %T", compiler.ByteCode().Constants[1])
+	}
+	if fn.Name != "double" {
+		t.Errorf("fn.Name = %q, want = %q", fn.Name, "double")
+	}
+
+	// OpGetLocal 0, OpConstant 0, OpMul at offset 5, OpReturnValue
+	pos, ok := fn.SourceMap.Lookup(5)
+	if !ok || pos.String() != "2:5" {
+		t.Errorf("fn.SourceMap.Lookup(5) = %s, %t, want = 2:5, true", pos, ok)
+	}
+}
+
 func TestCompileRecursiveFunctions(t *testing.T) {
 	testCases := []compilerTestCase{
 		{
@@ -102,7 +877,7 @@ func TestCompileClosures(t *testing.T) {
 					code.MakeInstruction(code.OpReturnValue),
 				},
 				[]code.Instructions{
-					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpGetLocalCell, 0),
 					code.MakeInstruction(code.OpClosure, 0, 1),
 					code.MakeInstruction(code.OpReturnValue),
 				},
@@ -132,13 +907,13 @@ func TestCompileClosures(t *testing.T) {
 					code.MakeInstruction(code.OpReturnValue),
 				},
 				[]code.Instructions{
-					code.MakeInstruction(code.OpGetFree, 0),
-					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpGetFreeCell, 0),
+					code.MakeInstruction(code.OpGetLocalCell, 0),
 					code.MakeInstruction(code.OpClosure, 0, 2),
 					code.MakeInstruction(code.OpReturnValue),
 				},
 				[]code.Instructions{
-					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpGetLocalCell, 0),
 					code.MakeInstruction(code.OpClosure, 1, 1),
 					code.MakeInstruction(code.OpReturnValue),
 				},
@@ -182,15 +957,15 @@ func TestCompileClosures(t *testing.T) {
 				[]code.Instructions{
 					code.MakeInstruction(code.OpConstant, 2),
 					code.MakeInstruction(code.OpSetLocal, 0),
-					code.MakeInstruction(code.OpGetFree, 0),
-					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpGetFreeCell, 0),
+					code.MakeInstruction(code.OpGetLocalCell, 0),
 					code.MakeInstruction(code.OpClosure, 4, 2),
 					code.MakeInstruction(code.OpReturnValue),
 				},
 				[]code.Instructions{
 					code.MakeInstruction(code.OpConstant, 1),
 					code.MakeInstruction(code.OpSetLocal, 0),
-					code.MakeInstruction(code.OpGetLocal, 0),
+					code.MakeInstruction(code.OpGetLocalCell, 0),
 					code.MakeInstruction(code.OpClosure, 5, 1),
 					code.MakeInstruction(code.OpReturnValue),
 				},
@@ -842,11 +1617,11 @@ func TestCompileBooleanExpressions(t *testing.T) {
 		},
 		{
 			input:             "1 < 2",
-			expectedConstants: []any{2, 1},
+			expectedConstants: []any{1, 2},
 			expectedInstructions: []code.Instructions{
 				code.MakeInstruction(code.OpConstant, 0),
 				code.MakeInstruction(code.OpConstant, 1),
-				code.MakeInstruction(code.OpGreaterThan),
+				code.MakeInstruction(code.OpLessThan),
 				code.MakeInstruction(code.OpPop),
 			},
 		},
@@ -915,6 +1690,16 @@ func TestCompileIntegerArithmetic(t *testing.T) {
 				code.MakeInstruction(code.OpPop),
 			},
 		},
+		{
+			input:             "5 % 2",
+			expectedConstants: []any{5, 2},
+			expectedInstructions: []code.Instructions{
+				code.MakeInstruction(code.OpConstant, 0),
+				code.MakeInstruction(code.OpConstant, 1),
+				code.MakeInstruction(code.OpMod),
+				code.MakeInstruction(code.OpPop),
+			},
+		},
 		{
 			input:             "-1",
 			expectedConstants: []any{1},
@@ -995,6 +1780,11 @@ func testConstants(t *testing.T, expected []any, actual []object.IObject) error
 			if err != nil {
 				return fmt.Errorf("testIntegerObject for constant %d failed: %s", i, err)
 			}
+		case float64:
+			err := testFloatObject(constant, actual[i])
+			if err != nil {
+				return fmt.Errorf("testFloatObject for constant %d failed: %s", i, err)
+			}
 		case string:
 			err := testStringObject(constant, actual[i])
 			if err != nil {
@@ -1027,6 +1817,18 @@ func testIntegerObject(expected int64, actual object.IObject) error {
 	return nil
 }
 
+func testFloatObject(expected float64, actual object.IObject) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("actual is not *object.Float. got = %T (%v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object.Value = %g, want = %g", result.Value, expected)
+	}
+	return nil
+}
+
 func testStringObject(expected string, actual object.IObject) error {
 	result, ok := actual.(*object.String)
 	if !ok {
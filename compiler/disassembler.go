@@ -0,0 +1,184 @@
+package compiler
+
+import (
+	"BigTalk_Interpreter/code"
+	"BigTalk_Interpreter/object"
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// Disassemble renders the whole program held in the bytecode: the main instructions
+// followed by every compiled function constant, discovered recursively through the
+// OpClosure instructions that create them. Constant operands are resolved to their
+// values and jump targets are shown as labels.
+func Disassemble(bytecode *ByteCode) string {
+	d := &disassembler{constants: bytecode.Constants, visited: map[int]bool{}}
+
+	d.function("main", bytecode.Instructions)
+	for len(d.pending) > 0 {
+		index := d.pending[0]
+		d.pending = d.pending[1:]
+
+		fn := d.constants[index].(*object.CompiledFunction)
+		title := fmt.Sprintf("%s (%s, locals=%d)", functionLabel(index, fn), parameterSummary(fn), fn.LocalsCount)
+		d.function(title, fn.Instructions)
+	}
+
+	// Function constants that no closure refers to are still part of the program.
+	for index, constant := range d.constants {
+		if fn, ok := constant.(*object.CompiledFunction); ok && !d.visited[index] {
+			d.visited[index] = true
+			title := fmt.Sprintf("%s (%s, locals=%d, unreferenced)", functionLabel(index, fn), parameterSummary(fn), fn.LocalsCount)
+			d.function(title, fn.Instructions)
+		}
+	}
+	return d.out.String()
+}
+
+// parameterSummary describes the parameters of fn, e.g. "params=2, optional=1, variadic".
+func parameterSummary(fn *object.CompiledFunction) string {
+	summary := fmt.Sprintf("params=%d", fn.ParametersCount)
+	if fn.OptionalParametersCount > 0 {
+		summary += fmt.Sprintf(", optional=%d", fn.OptionalParametersCount)
+	}
+	if fn.Variadic {
+		summary += ", variadic"
+	}
+	return summary
+}
+
+type disassembler struct {
+	out       bytes.Buffer
+	constants []object.IObject
+	visited   map[int]bool
+	pending   []int // function constants found but not rendered yet
+}
+
+func (d *disassembler) function(title string, ins code.Instructions) {
+	if d.out.Len() > 0 {
+		d.out.WriteString("\n")
+	}
+	fmt.Fprintf(&d.out, "== %s ==\n", title)
+
+	labels := jumpLabels(ins)
+
+	i := 0
+	for i < len(ins) {
+		if label, ok := labels[i]; ok {
+			fmt.Fprintf(&d.out, "%s:\n", label)
+		}
+
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&d.out, "  %04d ERROR: %s\n", i, err)
+			i++
+			continue
+		}
+
+		operands, read := code.ReadOperands(def, ins[i+1:])
+		line := fmt.Sprintf("  %04d %s", i, d.instruction(code.Opcode(ins[i]), def, operands, labels))
+		if comment := d.comment(code.Opcode(ins[i]), operands); comment != "" {
+			line = fmt.Sprintf("%-40s ; %s", line, comment)
+		}
+		d.out.WriteString(line + "\n")
+
+		i += 1 + read
+	}
+
+	if label, ok := labels[len(ins)]; ok {
+		fmt.Fprintf(&d.out, "%s:\n", label)
+	}
+}
+
+func (d *disassembler) instruction(op code.Opcode, def *code.OpcodeDefinition, operands []int, labels map[int]string) string {
+	out := def.Name
+	for i, operand := range operands {
+		if i == 0 && code.IsJump(op) {
+			out += " " + labels[operand]
+			continue
+		}
+		out += fmt.Sprintf(" %d", operand)
+	}
+	return out
+}
+
+// comment describes what an instruction's operands refer to.
+func (d *disassembler) comment(op code.Opcode, operands []int) string {
+	switch op {
+	case code.OpConstant:
+		return d.constant(operands[0])
+	case code.OpClosure:
+		d.visit(operands[0])
+		return fmt.Sprintf("%s, %d free", d.constant(operands[0]), operands[1])
+	case code.OpGetBuiltin:
+		if operands[0] < len(object.BuiltinFunctions) {
+			return "builtin " + object.BuiltinFunctions[operands[0]].Name
+		}
+	}
+	return ""
+}
+
+func (d *disassembler) constant(index int) string {
+	if index >= len(d.constants) {
+		return fmt.Sprintf("ERROR: constant %d out of range", index)
+	}
+
+	switch constant := d.constants[index].(type) {
+	case *object.CompiledFunction:
+		return functionLabel(index, constant)
+	case *object.String:
+		return fmt.Sprintf("%s %q", constant.Type(), constant.Value)
+	default:
+		return fmt.Sprintf("%s %s", constant.Type(), constant.Inspect())
+	}
+}
+
+func (d *disassembler) visit(index int) {
+	if index >= len(d.constants) || d.visited[index] {
+		return
+	}
+	if _, ok := d.constants[index].(*object.CompiledFunction); !ok {
+		return
+	}
+	d.visited[index] = true
+	d.pending = append(d.pending, index)
+}
+
+// functionLabel names the function constant fn at index, e.g. "fn#1 <add>" for a function
+// bound to add with let and "fn#1" for an anonymous one.
+func functionLabel(index int, fn *object.CompiledFunction) string {
+	if fn.Name != "" {
+		return fmt.Sprintf("fn#%d <%s>", index, fn.Name)
+	}
+	return fmt.Sprintf("fn#%d", index)
+}
+
+// jumpLabels names every jump target of the instructions, numbering them in offset order.
+func jumpLabels(ins code.Instructions) map[int]string {
+	var targets []int
+	seen := map[int]bool{}
+
+	i := 0
+	for i < len(ins) {
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			i++
+			continue
+		}
+
+		operands, read := code.ReadOperands(def, ins[i+1:])
+		if code.IsJump(code.Opcode(ins[i])) && !seen[operands[0]] {
+			seen[operands[0]] = true
+			targets = append(targets, operands[0])
+		}
+		i += 1 + read
+	}
+
+	sort.Ints(targets)
+	labels := make(map[int]string, len(targets))
+	for n, target := range targets {
+		labels[target] = fmt.Sprintf("L%d", n)
+	}
+	return labels
+}
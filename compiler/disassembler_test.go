@@ -0,0 +1,71 @@
+package compiler
+
+import "testing"
+
+func TestDisassembleParameterDefaults(t *testing.T) {
+	input := "let f = fn(a, b = 2, ...rest) { b };"
+	expected := `== main ==
+  0000 OpClosure 1 0                     ; fn#1 <f>, 0 free
+  0004 OpSetGlobal 0
+
+== fn#1 <f> (params=2, optional=1, variadic, locals=3) ==
+  0000 OpHasArgument 1
+  0002 OpJumpTruthy L0
+  0005 OpConstant 0                      ; INTEGER 2
+  0008 OpSetLocal 1
+L0:
+  0010 OpGetLocal 1
+  0012 OpReturnValue
+`
+
+	compiler := NewCompiler()
+	err := compiler.Compile(parse(input))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	got := Disassemble(compiler.ByteCode())
+	if got != expected {
+		t.Errorf("Disassemble() wrong.\n got =\n%s\n want =\n%s", got, expected)
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	input := `
+	let outer = fn(a) {
+		fn() { if (a) { "yes" } else { len("") } };
+	};
+	`
+	expected := `== main ==
+  0000 OpClosure 3 0                     ; fn#3 <outer>, 0 free
+  0004 OpSetGlobal 0
+
+== fn#3 <outer> (params=1, locals=1) ==
+  0000 OpGetLocalCell 0
+  0002 OpClosure 2 1                     ; fn#2, 1 free
+  0006 OpReturnValue
+
+== fn#2 (params=0, locals=0) ==
+  0000 OpGetFree 0
+  0002 OpJumpNotTruthy L0
+  0005 OpConstant 0                      ; STRING "yes"
+  0008 OpJump L1
+L0:
+  0011 OpGetBuiltin 0                    ; builtin len
+  0013 OpConstant 1                      ; STRING ""
+  0016 OpCall 1
+L1:
+  0018 OpReturnValue
+`
+
+	compiler := NewCompiler()
+	err := compiler.Compile(parse(input))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	got := Disassemble(compiler.ByteCode())
+	if got != expected {
+		t.Errorf("Disassemble() wrong.\n got =\n%s\n want =\n%s", got, expected)
+	}
+}
@@ -0,0 +1,474 @@
+package compiler
+
+import (
+	"BigTalk_Interpreter/code"
+	"BigTalk_Interpreter/object"
+	"BigTalk_Interpreter/token"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Serialized bytecode layout. All numbers are big endian.
+//
+//	magic        [4]byte  "BTC\x00"
+//	version      uint16   ByteCodeFormatVersion
+//	instructions uint32 length followed by the raw instruction bytes
+//	source map   source positions of the instructions
+//	constants    uint32 count followed by every constant
+//
+// Each constant starts with a one byte kind tag followed by its payload:
+//
+//	constInteger           int64
+//	constFloat             IEEE 754 binary64 bits
+//	constString            uint32 length + UTF-8 bytes
+//	constCompiledFunction  uint32 LocalsCount, uint32 ParametersCount,
+//	                       uint32 OptionalParametersCount, byte Variadic, name string,
+//	                       instructions, source map
+//
+// A source map is a uint32 count of file names, each one a string, followed by a uint32
+// count of entries. Every entry is five uint32: instruction offset, file name index, line,
+// column and byte offset.
+//
+// Compiled functions reference other constants through their instructions only, so nested
+// functions are simply further entries of the constant pool.
+//
+// The version is bumped whenever the layout or the instruction set changes, a VM cannot run
+// opcodes it does not know about.
+const ByteCodeFormatVersion uint16 = 13
+
+var byteCodeMagic = [4]byte{'B', 'T', 'C', 0}
+
+const (
+	constInteger byte = iota + 1
+	constString
+	constCompiledFunction
+	constFloat
+)
+
+// MarshalBinary encodes the bytecode into the versioned binary format.
+func (b *ByteCode) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.Write(byteCodeMagic[:])
+	writeUint16(&buf, ByteCodeFormatVersion)
+	writeBytes(&buf, b.Instructions)
+	writeSourceMap(&buf, b.SourceMap)
+
+	writeUint32(&buf, uint32(len(b.Constants)))
+	for i, constant := range b.Constants {
+		err := writeConstant(&buf, constant)
+		if err != nil {
+			return nil, fmt.Errorf("constant %d: %w", i, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes bytecode written by MarshalBinary. Data produced by a different
+// format version is rejected, and so are instructions the VM could not run safely.
+func (b *ByteCode) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil || magic != byteCodeMagic {
+		return errors.New("not a BigTalk bytecode file")
+	}
+
+	version, err := readUint16(r)
+	if err != nil {
+		return err
+	}
+	if version != ByteCodeFormatVersion {
+		return fmt.Errorf("unsupported bytecode version %d, want %d", version, ByteCodeFormatVersion)
+	}
+
+	instructions, err := readBytes(r)
+	if err != nil {
+		return err
+	}
+
+	sourceMap, err := readSourceMap(r)
+	if err != nil {
+		return err
+	}
+
+	count, err := readUint32(r)
+	if err != nil {
+		return err
+	}
+	// Every constant takes at least its kind byte, a larger count cannot be right and must
+	// not size the constant pool.
+	if int64(count) > int64(r.Len()) {
+		return fmt.Errorf("constant count %d exceeds the remaining %d bytes", count, r.Len())
+	}
+
+	constants := make([]object.IObject, 0, count)
+	for i := uint32(0); i < count; i++ {
+		constant, err := readConstant(r)
+		if err != nil {
+			return fmt.Errorf("constant %d: %w", i, err)
+		}
+		constants = append(constants, constant)
+	}
+
+	if r.Len() != 0 {
+		return fmt.Errorf("%d unexpected trailing bytes", r.Len())
+	}
+
+	err = validateByteCode(instructions, constants)
+	if err != nil {
+		return err
+	}
+
+	b.Instructions = instructions
+	b.SourceMap = sourceMap
+	b.Constants = constants
+	return nil
+}
+
+// validateByteCode checks decoded bytecode before the VM gets to see it. Besides the checks
+// of validateInstructions, every function must have room for its parameters among its local
+// slots and may only read the free variables its closures capture.
+func validateByteCode(instructions code.Instructions, constants []object.IObject) error {
+	freeCounts := map[int]int{} // free variables OpClosure captures, per function constant
+
+	freeUsed, err := validateInstructions(instructions, constants, 0, freeCounts)
+	if err == nil && freeUsed > 0 {
+		err = fmt.Errorf("free variable %d out of range", freeUsed-1)
+	}
+	if err != nil {
+		return fmt.Errorf("main instructions: %w", err)
+	}
+
+	functionsFreeUsed := map[int]int{}
+	for i, constant := range constants {
+		fn, ok := constant.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+
+		parameters := fn.ParametersCount
+		if fn.Variadic {
+			parameters++
+		}
+		if fn.LocalsCount > maxLocals || parameters > fn.LocalsCount || fn.OptionalParametersCount > fn.ParametersCount {
+			return fmt.Errorf("constant %d: invalid function with %d locals, %d parameters of which %d optional",
+				i, fn.LocalsCount, fn.ParametersCount, fn.OptionalParametersCount)
+		}
+
+		functionsFreeUsed[i], err = validateInstructions(fn.Instructions, constants, fn.LocalsCount, freeCounts)
+		if err != nil {
+			return fmt.Errorf("constant %d: %w", i, err)
+		}
+	}
+
+	for i, freeUsed := range functionsFreeUsed {
+		if count, ok := freeCounts[i]; ok && freeUsed > count {
+			return fmt.Errorf("constant %d: free variable %d out of range, its closures capture %d", i, freeUsed-1, count)
+		}
+	}
+	return nil
+}
+
+// maxLocals is the number of local slots the one byte operand of OpGetLocal can address.
+const maxLocals = 256
+
+// validateInstructions checks the instructions of a function with the given number of local
+// slots: every opcode must be known and followed by all of its operands, constant, builtin
+// and local indices must exist, OpClosure must refer to a compiled function and jumps must
+// land on the start of an instruction or at the end of the instructions. The number of free
+// variables each OpClosure captures is recorded in freeCounts, and the number of free
+// variables the instructions read is returned.
+func validateInstructions(ins code.Instructions, constants []object.IObject, locals int, freeCounts map[int]int) (int, error) {
+	starts := map[int]bool{len(ins): true}
+	var jumps [][2]int // offset of each jump instruction and its target
+	freeUsed := 0
+
+	for i := 0; i < len(ins); {
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			return 0, fmt.Errorf("%04d: %w", i, err)
+		}
+
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if i+1+width > len(ins) {
+			return 0, fmt.Errorf("%04d: %s is missing operands", i, def.Name)
+		}
+		operands, _ := code.ReadOperands(def, ins[i+1:])
+
+		op := code.Opcode(ins[i])
+		switch op {
+		case code.OpConstant, code.OpClosure:
+			if operands[0] >= len(constants) {
+				return 0, fmt.Errorf("%04d: constant %d out of range", i, operands[0])
+			}
+			if op == code.OpConstant {
+				break
+			}
+			if _, ok := constants[operands[0]].(*object.CompiledFunction); !ok {
+				return 0, fmt.Errorf("%04d: constant %d is not a function", i, operands[0])
+			}
+			if count, ok := freeCounts[operands[0]]; ok && count != operands[1] {
+				return 0, fmt.Errorf("%04d: function %d captures %d free variables, elsewhere %d",
+					i, operands[0], operands[1], count)
+			}
+			freeCounts[operands[0]] = operands[1]
+		case code.OpGetBuiltin:
+			if operands[0] >= len(object.BuiltinFunctions) {
+				return 0, fmt.Errorf("%04d: builtin %d out of range", i, operands[0])
+			}
+		case code.OpGetLocal, code.OpSetLocal, code.OpGetLocalCell, code.OpHasArgument:
+			if operands[0] >= locals {
+				return 0, fmt.Errorf("%04d: local %d out of range", i, operands[0])
+			}
+		case code.OpGetFree, code.OpGetFreeCell, code.OpSetFree:
+			freeUsed = max(freeUsed, operands[0]+1)
+		}
+		if code.IsJump(op) {
+			jumps = append(jumps, [2]int{i, operands[0]})
+		}
+
+		starts[i] = true
+		i += 1 + width
+	}
+
+	for _, jump := range jumps {
+		if !starts[jump[1]] {
+			return 0, fmt.Errorf("%04d: jump target %04d is not the start of an instruction", jump[0], jump[1])
+		}
+	}
+	return freeUsed, nil
+}
+
+func writeConstant(buf *bytes.Buffer, obj object.IObject) error {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		buf.WriteByte(constInteger)
+		writeUint64(buf, uint64(obj.Value))
+	case *object.Float:
+		buf.WriteByte(constFloat)
+		writeUint64(buf, math.Float64bits(obj.Value))
+	case *object.String:
+		buf.WriteByte(constString)
+		writeBytes(buf, []byte(obj.Value))
+	case *object.CompiledFunction:
+		buf.WriteByte(constCompiledFunction)
+		writeUint32(buf, uint32(obj.LocalsCount))
+		writeUint32(buf, uint32(obj.ParametersCount))
+		writeUint32(buf, uint32(obj.OptionalParametersCount))
+		if obj.Variadic {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		writeBytes(buf, []byte(obj.Name))
+		writeBytes(buf, obj.Instructions)
+		writeSourceMap(buf, obj.SourceMap)
+	default:
+		return fmt.Errorf("cannot serialize constant of type %s", obj.Type())
+	}
+	return nil
+}
+
+func readConstant(r *bytes.Reader) (object.IObject, error) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	switch kind {
+	case constInteger:
+		value, err := readUint64(r)
+		if err != nil {
+			return nil, err
+		}
+		return &object.Integer{Value: int64(value)}, nil
+	case constFloat:
+		value, err := readUint64(r)
+		if err != nil {
+			return nil, err
+		}
+		return &object.Float{Value: math.Float64frombits(value)}, nil
+	case constString:
+		value, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		return &object.String{Value: string(value)}, nil
+	case constCompiledFunction:
+		localsCount, err := readUint32(r)
+		if err != nil {
+			return nil, err
+		}
+		parametersCount, err := readUint32(r)
+		if err != nil {
+			return nil, err
+		}
+		optionalCount, err := readUint32(r)
+		if err != nil {
+			return nil, err
+		}
+		variadic, err := r.ReadByte()
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		name, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		instructions, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		sourceMap, err := readSourceMap(r)
+		if err != nil {
+			return nil, err
+		}
+		return &object.CompiledFunction{
+			Instructions:            code.Instructions(instructions),
+			LocalsCount:             int(localsCount),
+			ParametersCount:         int(parametersCount),
+			OptionalParametersCount: int(optionalCount),
+			Variadic:                variadic == 1,
+			Name:                    string(name),
+			SourceMap:               sourceMap,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown constant kind %d", kind)
+	}
+}
+
+func writeSourceMap(buf *bytes.Buffer, sourceMap code.SourceMap) {
+	var filenames []string
+	fileIndex := map[string]int{}
+	for _, entry := range sourceMap {
+		if _, ok := fileIndex[entry.Pos.Filename]; !ok {
+			fileIndex[entry.Pos.Filename] = len(filenames)
+			filenames = append(filenames, entry.Pos.Filename)
+		}
+	}
+
+	writeUint32(buf, uint32(len(filenames)))
+	for _, filename := range filenames {
+		writeBytes(buf, []byte(filename))
+	}
+
+	writeUint32(buf, uint32(len(sourceMap)))
+	for _, entry := range sourceMap {
+		writeUint32(buf, uint32(entry.Offset))
+		writeUint32(buf, uint32(fileIndex[entry.Pos.Filename]))
+		writeUint32(buf, uint32(entry.Pos.Line))
+		writeUint32(buf, uint32(entry.Pos.Column))
+		writeUint32(buf, uint32(entry.Pos.Offset))
+	}
+}
+
+func readSourceMap(r *bytes.Reader) (code.SourceMap, error) {
+	fileCount, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var filenames []string
+	for i := uint32(0); i < fileCount; i++ {
+		filename, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		filenames = append(filenames, string(filename))
+	}
+
+	count, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var sourceMap code.SourceMap
+	for i := uint32(0); i < count; i++ {
+		var fields [5]uint32
+		for j := range fields {
+			fields[j], err = readUint32(r)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if int(fields[1]) >= len(filenames) {
+			return nil, fmt.Errorf("source map entry %d: file index %d out of range", i, fields[1])
+		}
+
+		sourceMap = append(sourceMap, code.SourceMapping{
+			Offset: int(fields[0]),
+			Pos: token.Position{
+				Filename: filenames[fields[1]],
+				Line:     int(fields[2]),
+				Column:   int(fields[3]),
+				Offset:   int(fields[4]),
+			},
+		})
+	}
+	return sourceMap, nil
+}
+
+func writeUint16(buf *bytes.Buffer, v uint16) {
+	buf.Write(binary.BigEndian.AppendUint16(nil, v))
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	buf.Write(binary.BigEndian.AppendUint32(nil, v))
+}
+
+func writeUint64(buf *bytes.Buffer, v uint64) {
+	buf.Write(binary.BigEndian.AppendUint64(nil, v))
+}
+
+func writeBytes(buf *bytes.Buffer, b []byte) {
+	writeUint32(buf, uint32(len(b)))
+	buf.Write(b)
+}
+
+func readUint16(r *bytes.Reader) (uint16, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return binary.BigEndian.Uint16(b[:]), nil
+}
+
+func readUint32(r *bytes.Reader) (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return binary.BigEndian.Uint32(b[:]), nil
+}
+
+func readUint64(r *bytes.Reader) (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	length, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if int64(length) > int64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b, nil
+}
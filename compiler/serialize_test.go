@@ -0,0 +1,244 @@
+package compiler
+
+import (
+	"BigTalk_Interpreter/code"
+	"BigTalk_Interpreter/object"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestByteCodeUnmarshalInvalidInstructions(t *testing.T) {
+	function := &object.CompiledFunction{Instructions: code.MakeInstruction(code.OpConstant, 3)}
+
+	testCases := []struct {
+		name     string
+		bytecode *ByteCode
+		expected string
+	}{
+		{
+			"unknown opcode",
+			&ByteCode{Instructions: code.Instructions{255}},
+			"main instructions: 0000: opcode 255 undefined",
+		},
+		{
+			"missing operands",
+			&ByteCode{Instructions: code.MakeInstruction(code.OpConstant, 0)[:2]},
+			"main instructions: 0000: OpConstant is missing operands",
+		},
+		{
+			"constant out of range",
+			&ByteCode{Instructions: code.MakeInstruction(code.OpConstant, 5)},
+			"main instructions: 0000: constant 5 out of range",
+		},
+		{
+			"closure of a non-function",
+			&ByteCode{
+				Instructions: code.MakeInstruction(code.OpClosure, 0, 0),
+				Constants:    []object.IObject{&object.Integer{Value: 1}},
+			},
+			"main instructions: 0000: constant 0 is not a function",
+		},
+		{
+			"builtin out of range",
+			&ByteCode{Instructions: code.MakeInstruction(code.OpGetBuiltin, 200)},
+			"main instructions: 0000: builtin 200 out of range",
+		},
+		{
+			"jump into an instruction",
+			&ByteCode{Instructions: concatInstructions([]code.Instructions{
+				code.MakeInstruction(code.OpTrue),
+				code.MakeInstruction(code.OpJump, 2),
+			})},
+			"main instructions: 0001: jump target 0002 is not the start of an instruction",
+		},
+		{
+			"jump past the end",
+			&ByteCode{Instructions: code.MakeInstruction(code.OpJumpNotTruthy, 4)},
+			"main instructions: 0000: jump target 0004 is not the start of an instruction",
+		},
+		{
+			"handler past the end",
+			&ByteCode{Instructions: code.MakeInstruction(code.OpTry, 100)},
+			"main instructions: 0000: jump target 0100 is not the start of an instruction",
+		},
+		{
+			"local in the main instructions",
+			&ByteCode{Instructions: code.MakeInstruction(code.OpGetLocal, 0)},
+			"main instructions: 0000: local 0 out of range",
+		},
+		{
+			"free variable in the main instructions",
+			&ByteCode{Instructions: code.MakeInstruction(code.OpGetFree, 0)},
+			"main instructions: free variable 0 out of range",
+		},
+		{
+			"local out of range",
+			&ByteCode{
+				Instructions: code.MakeInstruction(code.OpClosure, 0, 0),
+				Constants: []object.IObject{&object.CompiledFunction{
+					Instructions: code.MakeInstruction(code.OpSetLocal, 3),
+					LocalsCount:  1,
+				}},
+			},
+			"constant 0: 0000: local 3 out of range",
+		},
+		{
+			"free variable out of range",
+			&ByteCode{
+				Instructions: concatInstructions([]code.Instructions{
+					code.MakeInstruction(code.OpNull),
+					code.MakeInstruction(code.OpClosure, 0, 1),
+				}),
+				Constants: []object.IObject{&object.CompiledFunction{
+					Instructions: code.MakeInstruction(code.OpGetFree, 1),
+				}},
+			},
+			"constant 0: free variable 1 out of range, its closures capture 1",
+		},
+		{
+			"parameters without locals",
+			&ByteCode{Constants: []object.IObject{&object.CompiledFunction{ParametersCount: 2, LocalsCount: 1}}},
+			"constant 0: invalid function with 1 locals, 2 parameters of which 0 optional",
+		},
+		{
+			"invalid function",
+			&ByteCode{
+				Instructions: code.MakeInstruction(code.OpClosure, 0, 0),
+				Constants:    []object.IObject{function},
+			},
+			"constant 0: 0000: constant 3 out of range",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.bytecode.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() error: %s", err)
+			}
+
+			err = (&ByteCode{}).UnmarshalBinary(data)
+			if err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+			if err.Error() != tc.expected {
+				t.Errorf("err = %q, want = %q", err, tc.expected)
+			}
+		})
+	}
+}
+
+func TestByteCodeMarshalRoundTrip(t *testing.T) {
+	input := `
+	let greeting = "hello";
+	let newAdder = fn(a, b) {
+		let c = a + b;
+		fn(d, e = 1, ...rest) { c + d + -9223372036854775807 * 0.5e-3 };
+	};
+	newAdder(1, 2)(3);
+	`
+
+	compiler := NewCompiler()
+	err := compiler.Compile(parse(input))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	original := compiler.ByteCode()
+
+	data, err := original.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error: %s", err)
+	}
+
+	decoded := &ByteCode{}
+	err = decoded.UnmarshalBinary(data)
+	if err != nil {
+		t.Fatalf("UnmarshalBinary() error: %s", err)
+	}
+
+	if decoded.Instructions.String() != original.Instructions.String() {
+		t.Errorf("instructions differ. \n got = %q \n want = %q", decoded.Instructions, original.Instructions)
+	}
+
+	if !reflect.DeepEqual(decoded.SourceMap, original.SourceMap) {
+		t.Errorf("source map differs. \n got = %v \n want = %v", decoded.SourceMap, original.SourceMap)
+	}
+
+	if len(decoded.Constants) != len(original.Constants) {
+		t.Fatalf("len(decoded.Constants) = %d, want = %d", len(decoded.Constants), len(original.Constants))
+	}
+
+	for i, want := range original.Constants {
+		got := decoded.Constants[i]
+		if got.Type() != want.Type() {
+			t.Errorf("constant %d type = %s, want = %s", i, got.Type(), want.Type())
+			continue
+		}
+
+		switch want := want.(type) {
+		case *object.CompiledFunction:
+			fn := got.(*object.CompiledFunction)
+			if fn.Instructions.String() != want.Instructions.String() {
+				t.Errorf("constant %d instructions = %q, want = %q", i, fn.Instructions, want.Instructions)
+			}
+			if fn.Name != want.Name {
+				t.Errorf("constant %d name = %q, want = %q", i, fn.Name, want.Name)
+			}
+			if !reflect.DeepEqual(fn.SourceMap, want.SourceMap) {
+				t.Errorf("constant %d source map = %v, want = %v", i, fn.SourceMap, want.SourceMap)
+			}
+			if fn.LocalsCount != want.LocalsCount || fn.ParametersCount != want.ParametersCount {
+				t.Errorf("constant %d counts = (%d, %d), want = (%d, %d)", i,
+					fn.LocalsCount, fn.ParametersCount, want.LocalsCount, want.ParametersCount)
+			}
+			if fn.OptionalParametersCount != want.OptionalParametersCount || fn.Variadic != want.Variadic {
+				t.Errorf("constant %d optional, variadic = (%d, %t), want = (%d, %t)", i,
+					fn.OptionalParametersCount, fn.Variadic, want.OptionalParametersCount, want.Variadic)
+			}
+		default:
+			if got.Inspect() != want.Inspect() {
+				t.Errorf("constant %d = %s, want = %s", i, got.Inspect(), want.Inspect())
+			}
+		}
+	}
+}
+
+func TestByteCodeUnmarshalErrors(t *testing.T) {
+	valid, err := (&ByteCode{}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error: %s", err)
+	}
+
+	wrongVersion := append([]byte{}, valid...)
+	wrongVersion[5]++
+
+	hugeConstantCount := append([]byte{}, valid...)
+	copy(hugeConstantCount[len(hugeConstantCount)-4:], []byte{0xff, 0xff, 0xff, 0xff})
+
+	testCases := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"empty", []byte{}, "not a BigTalk bytecode file"},
+		{"bad magic", []byte("#!/bin/sh"), "not a BigTalk bytecode file"},
+		{"wrong version", wrongVersion, fmt.Sprintf("unsupported bytecode version %d, want %d",
+			ByteCodeFormatVersion+1, ByteCodeFormatVersion)},
+		{"truncated", valid[:len(valid)-1], "unexpected EOF"},
+		{"huge constant count", hugeConstantCount, "constant count 4294967295 exceeds the remaining 0 bytes"},
+		{"trailing bytes", append(append([]byte{}, valid...), 0), "1 unexpected trailing bytes"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := (&ByteCode{}).UnmarshalBinary(tc.data)
+			if err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+			if err.Error() != tc.expected {
+				t.Errorf("err = %q, want = %q", err, tc.expected)
+			}
+		})
+	}
+}
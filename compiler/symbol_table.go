@@ -1,5 +1,10 @@
 package compiler
 
+import (
+	"sort"
+	"strings"
+)
+
 type SymbolScope string
 
 const (
@@ -16,12 +21,34 @@ type Symbol struct {
 	Index int
 }
 
+// hiddenSymbolPrefix starts the names of bindings the compiler introduces itself. No
+// identifier can start with it, so source code cannot refer to them.
+const hiddenSymbolPrefix = "$"
+
+// Hidden reports whether the symbol is a binding the compiler introduced itself.
+func (s Symbol) Hidden() bool {
+	return strings.HasPrefix(s.Name, hiddenSymbolPrefix)
+}
+
 type SymbolTable struct {
 	Outer *SymbolTable
 
 	store          map[string]Symbol
 	numDefinitions int
 	FreeSymbols    []Symbol
+
+	block *blockScope
+}
+
+// blockScope records the bindings that the names defined in a block shadow.
+type blockScope struct {
+	outer    *blockScope
+	shadowed map[string]shadowedSymbol
+}
+
+type shadowedSymbol struct {
+	symbol Symbol
+	ok     bool
 }
 
 func NewSymbolTable() *SymbolTable {
@@ -35,6 +62,11 @@ func NewWrappedSymbolTable(outer *SymbolTable) *SymbolTable {
 	return s
 }
 
+// Define binds name in this table. Like in the evaluator, defining a name again in the same
+// scope rebinds the existing variable instead of shadowing it: the slot is reused, so
+// `let x = x + 1` in a loop body updates the binding the loop condition reads and functions
+// referring to x see the new value. The first definition of a name inside a block started
+// with enterBlock gets a new slot instead.
 func (s *SymbolTable) Define(name string) Symbol {
 	symbol := Symbol{Name: name, Index: s.numDefinitions}
 	if s.Outer == nil {
@@ -42,6 +74,17 @@ func (s *SymbolTable) Define(name string) Symbol {
 	} else {
 		symbol.Scope = LocalScope
 	}
+
+	existing, ok := s.store[name]
+	if s.block != nil {
+		if _, defined := s.block.shadowed[name]; !defined {
+			s.block.shadowed[name] = shadowedSymbol{symbol: existing, ok: ok}
+			ok = false
+		}
+	}
+	if ok && existing.Scope == symbol.Scope {
+		return existing
+	}
 	s.store[name] = symbol
 	s.numDefinitions++
 	return symbol
@@ -86,3 +129,74 @@ func (s *SymbolTable) defineFreeSymbol(original Symbol) Symbol {
 	s.store[original.Name] = symbol
 	return symbol
 }
+
+// Symbols returns every symbol defined directly in this table ordered by scope and index.
+func (s *SymbolTable) Symbols() []Symbol {
+	symbols := make([]Symbol, 0, len(s.store))
+	for _, sym := range s.store {
+		symbols = append(symbols, sym)
+	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		if symbols[i].Scope != symbols[j].Scope {
+			return symbols[i].Scope < symbols[j].Scope
+		}
+		return symbols[i].Index < symbols[j].Index
+	})
+	return symbols
+}
+
+// hide removes names from the table, so they resolve to the enclosing scopes, until the
+// returned function restores them.
+func (s *SymbolTable) hide(names []string) (restore func()) {
+	hidden := make(map[string]Symbol, len(names))
+	for _, name := range names {
+		if symbol, ok := s.store[name]; ok {
+			hidden[name] = symbol
+			delete(s.store, name)
+		}
+	}
+
+	return func() {
+		for name, symbol := range hidden {
+			s.store[name] = symbol
+		}
+	}
+}
+
+// enterBlock starts a block scope: the names defined until the returned function ends it
+// are local to the block and shadow their bindings outside of it.
+func (s *SymbolTable) enterBlock() (exit func()) {
+	block := &blockScope{outer: s.block, shadowed: make(map[string]shadowedSymbol)}
+	s.block = block
+
+	return func() {
+		for name, shadowed := range block.shadowed {
+			if shadowed.ok {
+				s.store[name] = shadowed.symbol
+			} else {
+				delete(s.store, name)
+			}
+		}
+		s.block = block.outer
+	}
+}
+
+// Copy returns a copy of the table that can be defined into without affecting the original.
+// The outer table is shared.
+func (s *SymbolTable) Copy() *SymbolTable {
+	store := make(map[string]Symbol, len(s.store))
+	for name, sym := range s.store {
+		store[name] = sym
+	}
+
+	free := make([]Symbol, len(s.FreeSymbols))
+	copy(free, s.FreeSymbols)
+
+	return &SymbolTable{
+		Outer:          s.Outer,
+		store:          store,
+		numDefinitions: s.numDefinitions,
+		FreeSymbols:    free,
+	}
+}
@@ -2,6 +2,96 @@ package compiler
 
 import "testing"
 
+func TestSymbolTableEnterBlock(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	exit := global.enterBlock()
+	expected := Symbol{Name: "a", Scope: GlobalScope, Index: 1}
+	if a := global.Define("a"); a != expected {
+		t.Errorf("a in the block=%+v, want = %+v", a, expected)
+	}
+	if a := global.Define("a"); a != expected {
+		t.Errorf("a redefined in the block=%+v, want = %+v", a, expected)
+	}
+	global.Define("b")
+	exit()
+
+	expected = Symbol{Name: "a", Scope: GlobalScope, Index: 0}
+	if a, _ := global.Resolve("a"); a != expected {
+		t.Errorf("a after the block=%+v, want = %+v", a, expected)
+	}
+	if _, ok := global.Resolve("b"); ok {
+		t.Errorf("b defined in the block is still defined after it")
+	}
+}
+
+func TestSymbolTable_Redefine(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.Define("b")
+
+	expected := Symbol{Name: "a", Scope: GlobalScope, Index: 0}
+	if a := global.Define("a"); a != expected {
+		t.Errorf("redefined a=%+v, want = %+v", a, expected)
+	}
+	if c := global.Define("c"); c.Index != 2 {
+		t.Errorf("c.Index = %d, want = %d", c.Index, 2)
+	}
+
+	local := NewWrappedSymbolTable(global)
+	local.Resolve("a")
+	expected = Symbol{Name: "a", Scope: LocalScope, Index: 0}
+	if a := local.Define("a"); a != expected {
+		t.Errorf("local a=%+v, want = %+v", a, expected)
+	}
+}
+
+func TestSymbolTableCopy(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	copied := global.Copy()
+	copied.Define("b")
+
+	if _, ok := global.Resolve("b"); ok {
+		t.Errorf("symbol defined in the copy leaked into the original table")
+	}
+
+	expected := Symbol{Name: "b", Scope: GlobalScope, Index: 1}
+	result, ok := copied.Resolve("b")
+	if !ok {
+		t.Fatalf("name %q could not be resolved in the copy", expected.Name)
+	}
+	if result != expected {
+		t.Errorf("expected %q to resolve to %+v, got=%+v", expected.Name, expected, result)
+	}
+}
+
+func TestSymbolTableSymbols(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("b")
+	global.Define("a")
+	global.DefineBuiltin(0, "len")
+
+	expected := []Symbol{
+		{Name: "len", Scope: BuiltinScope, Index: 0},
+		{Name: "b", Scope: GlobalScope, Index: 0},
+		{Name: "a", Scope: GlobalScope, Index: 1},
+	}
+
+	result := global.Symbols()
+	if len(result) != len(expected) {
+		t.Fatalf("wrong number of symbols. got=%d, want=%d", len(result), len(expected))
+	}
+
+	for i, sym := range expected {
+		if result[i] != sym {
+			t.Errorf("symbols[%d] = %+v, want=%+v", i, result[i], sym)
+		}
+	}
+}
+
 func TestSymbolTableShadowingFunctionName(t *testing.T) {
 	global := NewSymbolTable()
 	global.DefineFunctionName("a")
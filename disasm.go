@@ -0,0 +1,49 @@
+package main
+
+import (
+	"BigTalk_Interpreter/compiler"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// disasmCommand implements `bigtalk disasm <file>`. Script files are compiled first,
+// files with the bytecode extension are read as they are.
+func disasmCommand(args []string) int {
+	fs := newFlagSet("disasm")
+
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(files) != 1 {
+		fmt.Fprintf(os.Stderr, "disasm: expected exactly one file argument\n\n%s", usage)
+		return 2
+	}
+	path := files[0]
+
+	var bytecode *compiler.ByteCode
+	if filepath.Ext(path) == byteCodeExtension {
+		bytecode, err = readByteCodeFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "disasm: %s\n", err)
+			return 1
+		}
+	} else {
+		program, ok := parseSourceFile(path)
+		if !ok {
+			return 1
+		}
+
+		comp := compiler.NewCompiler()
+		err = comp.Compile(program)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: compilation error: %s\n", path, err)
+			return 1
+		}
+		bytecode = comp.ByteCode()
+	}
+
+	fmt.Print(compiler.Disassemble(bytecode))
+	return 0
+}
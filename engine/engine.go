@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"BigTalk_Interpreter/ast"
+	"BigTalk_Interpreter/object"
+	"BigTalk_Interpreter/vm"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	EvalEngine = "eval"
+	VMEngine   = "vm"
+
+	DefaultEngine = VMEngine
+)
+
+// IEngine executes parsed BigTalk programs. An engine keeps its bindings between
+// calls to Run, so the same instance can back a whole REPL session.
+type IEngine interface {
+	Name() string
+	// Run executes the program and returns the value of its last expression,
+	// which may be nil when the program does not produce one.
+	Run(program *ast.Program) (object.IObject, error)
+	// Reset discards every binding created by previous calls to Run.
+	Reset()
+}
+
+type Stage string
+
+const (
+	CompileStage Stage = "compilation"
+	RuntimeStage Stage = "runtime"
+)
+
+// Error is returned by IEngine.Run and records which stage of the pipeline failed.
+type Error struct {
+	Stage Stage
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+var constructors = map[string]func() IEngine{
+	EvalEngine: func() IEngine { return NewEvaluator() },
+	VMEngine:   func() IEngine { return NewVM() },
+}
+
+// New returns a fresh engine registered under the given name.
+func New(name string) (IEngine, error) {
+	constructor, ok := constructors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown engine %q, want one of %v", name, Names())
+	}
+	return constructor(), nil
+}
+
+// Names returns the sorted names of all available engines.
+func Names() []string {
+	var names []string
+	for name := range constructors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StageOf reports the pipeline stage an error returned by IEngine.Run came from.
+// Errors that were not produced by an engine are attributed to the runtime.
+func StageOf(err error) Stage {
+	var engineErr *Error
+	if errors.As(err, &engineErr) {
+		return engineErr.Stage
+	}
+	return RuntimeStage
+}
+
+// StackTrace returns the BigTalk stack trace carried by a runtime error, or an empty string
+// when the engine that produced it does not record one.
+func StackTrace(err error) string {
+	var runtimeErr *vm.RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return runtimeErr.StackTrace()
+	}
+	return ""
+}
@@ -0,0 +1,277 @@
+package engine
+
+import (
+	"BigTalk_Interpreter/ast"
+	"BigTalk_Interpreter/lexer"
+	"BigTalk_Interpreter/parser"
+	"testing"
+)
+
+func TestEnginesScopeTheCatchParameter(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"let e = 1; try { throw 2 } catch (e) { 0 }; e", "1"},
+		{"let e = 1; let r = try { throw 2 } catch (e) { let e = 3; e }; [r, e]", "[3, 1]"},
+		{"let f = fn(e) { try { throw 2 } catch (e) { 0 } finally { e += 1 }; e }; f(5)", "6"},
+		{"let x = 1; try { throw 2 } catch (e) { x = 7 }; x", "7"},
+	}
+
+	for _, tc := range testCases {
+		for _, name := range Names() {
+			eng, err := New(name)
+			if err != nil {
+				t.Fatalf("New(%q) error: %s", name, err)
+			}
+			result, err := eng.Run(parse(t, tc.input))
+			if err != nil {
+				t.Fatalf("%s: Run(%q) error: %s", name, tc.input, err)
+			}
+			if result.Inspect() != tc.expected {
+				t.Errorf("%s: %q = %s, want = %s", name, tc.input, result.Inspect(), tc.expected)
+			}
+		}
+	}
+}
+
+func TestEnginesScopeMatchBindingsToTheArm(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = 9; match ([1, 2]) { [a, 3] => a, _ => a }", "9"},
+		{"let a = 9; let r = match ([1, 2]) { [a, 2] => a }; [r, a]", "[1, 9]"},
+		{"let a = 9; let r = match (1) { a if a > 5 => 0, [a] => 0, a => a + 1 }; [r, a]", "[2, 9]"},
+		{"let y = 0; match (1) { x => { let y = x + 1; y } } + y", "2"},
+		{"let f = fn(a) { let b = match ([a]) { [a] if a > 1 => a * 10, _ => a }; [a, b] }; [f(1), f(2)]", "[[1, 1], [2, 20]]"},
+		{"let f = fn() { let g = match (5) { n => fn() { n } }; let n = 6; g() }; f()", "5"},
+	}
+
+	for _, tc := range testCases {
+		for _, name := range Names() {
+			eng, err := New(name)
+			if err != nil {
+				t.Fatalf("New(%q) error: %s", name, err)
+			}
+			result, err := eng.Run(parse(t, tc.input))
+			if err != nil {
+				t.Fatalf("%s: Run(%q) error: %s", name, tc.input, err)
+			}
+			if result.Inspect() != tc.expected {
+				t.Errorf("%s: %q = %s, want = %s", name, tc.input, result.Inspect(), tc.expected)
+			}
+		}
+	}
+}
+
+func TestEnginesAgreeOnLoopControl(t *testing.T) {
+	// The value of an expression cannot be left half computed by break or continue.
+	p := parser.NewParser(lexer.NewLexer("let i = 0; while (i < 5000) { i += 1; [1, if (true) { continue; } else { 2 }] }"))
+	p.ParseProgram()
+	expected := "1:55: continue inside an expression whose value is used"
+	if len(p.Errors()) != 1 || p.Errors()[0] != expected {
+		t.Errorf("p.Errors() = %q, want = [%q]", p.Errors(), expected)
+	}
+
+	inputs := []string{
+		"let i = 0; let n = 0; while (i < 5000) { i += 1; if (true) { continue; } else { n += 1 } }; [i, n]",
+		"let i = 0; let s = []; while (i < 5000) { i += 1; if (i % 1000 != 0) { continue } s = push(s, [1, i]) }; s",
+		"let i = 0; while (true) { i += 1; try { if (i == 5000) { break } } finally { i += 0 } }; i",
+		"let i = 0; let s = 0; while (i < 5000) { i += 1; match ([i, 2]) { [a, 2] if a % 2 == 0 => { continue }, _ => { s += 1 } } }; s",
+	}
+
+	for _, input := range inputs {
+		var results []string
+		for _, name := range Names() {
+			eng, err := New(name)
+			if err != nil {
+				t.Fatalf("New(%q) error: %s", name, err)
+			}
+			result, err := eng.Run(parse(t, input))
+			if err != nil {
+				t.Fatalf("%s: Run(%q) error: %s", name, input, err)
+			}
+			results = append(results, result.Inspect())
+		}
+
+		for i, result := range results[1:] {
+			if result != results[0] {
+				t.Errorf("%q: %s engine = %s, %s engine = %s", input, Names()[0], results[0], Names()[i+1], result)
+			}
+		}
+	}
+}
+
+func TestEnginesAgreeOnCaughtErrors(t *testing.T) {
+	inputs := []string{
+		`try { 1 / 0 } catch (e) { [e["kind"], e["message"], e["stack"]] }`,
+		`try { len(1) } catch (e) { e["message"] }`,
+		`let f = fn() { throw "boom" }; let g = fn() { f() }; try { g() } catch (e) { [e["kind"], e["message"], e["stack"]] }`,
+		`try { throw {"kind": "NotFound", "message": "no user"} } catch (e) { [e["kind"], e["message"]] }`,
+		`let log = []; try { try { throw 1 } finally { log = push(log, "inner") } } catch (e) { push(log, e["message"]) }`,
+	}
+
+	for _, input := range inputs {
+		var results []string
+		for _, name := range Names() {
+			eng, err := New(name)
+			if err != nil {
+				t.Fatalf("New(%q) error: %s", name, err)
+			}
+			result, err := eng.Run(parse(t, input))
+			if err != nil {
+				t.Fatalf("%s: Run(%q) error: %s", name, input, err)
+			}
+			results = append(results, result.Inspect())
+		}
+
+		for i, result := range results[1:] {
+			if result != results[0] {
+				t.Errorf("%q: %s engine = %s, %s engine = %s", input, Names()[0], results[0], Names()[i+1], result)
+			}
+		}
+	}
+}
+
+func TestEnginesAgreeOnNumbers(t *testing.T) {
+	inputs := []string{
+		"1 + 2.5", "2.5 - 1", "3 * 0.1", "1 / 3.0", "7 / 2", "-1.5 * -2", "1e3 + 1",
+		"1 == 1.0", "0.1 + 0.2 == 0.3", "2.0 > 1", "1 < 1.0", "2.0 * 2", "-0.0",
+		"7 % 3", "-7 % 3", "7 % -3", "-7 / 2", "7.5 % -2", "-7.5 % 2",
+	}
+
+	for _, input := range inputs {
+		var results []string
+		for _, name := range Names() {
+			eng, err := New(name)
+			if err != nil {
+				t.Fatalf("New(%q) error: %s", name, err)
+			}
+			result, err := eng.Run(parse(t, input))
+			if err != nil {
+				t.Fatalf("%s: Run(%q) error: %s", name, input, err)
+			}
+			results = append(results, result.Inspect())
+		}
+
+		for i, result := range results[1:] {
+			if result != results[0] {
+				t.Errorf("%q: %s engine = %s, %s engine = %s", input, Names()[0], results[0], Names()[i+1], result)
+			}
+		}
+	}
+}
+
+func TestEnginesAgreeOnDivisionByZero(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"1 / 0", "division by zero"},
+		{"1 % 0", "modulo by zero"},
+	}
+
+	for _, tc := range testCases {
+		for _, name := range Names() {
+			eng, err := New(name)
+			if err != nil {
+				t.Fatalf("New(%q) error: %s", name, err)
+			}
+
+			_, err = eng.Run(parse(t, tc.input))
+			if err == nil || err.Error() != tc.expected {
+				t.Errorf("%s: Run(%q) error = %v, want = %q", name, tc.input, err, tc.expected)
+			}
+			if StageOf(err) != RuntimeStage {
+				t.Errorf("%s: StageOf(%q) = %q, want = %q", name, err, StageOf(err), RuntimeStage)
+			}
+		}
+	}
+}
+
+func TestEnginesKeepStateBetweenRuns(t *testing.T) {
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			eng, err := New(name)
+			if err != nil {
+				t.Fatalf("New(%q) error: %s", name, err)
+			}
+
+			inputs := []string{
+				"let add = fn(a, b) { a + b };",
+				"let x = add(2, 3);",
+				"x * 2",
+			}
+
+			var got string
+			for _, input := range inputs {
+				result, err := eng.Run(parse(t, input))
+				if err != nil {
+					t.Fatalf("Run(%q) error: %s", input, err)
+				}
+				if result != nil {
+					got = result.Inspect()
+				}
+			}
+
+			if got != "10" {
+				t.Errorf("last result = %q, want = %q", got, "10")
+			}
+
+			eng.Reset()
+			_, err = eng.Run(parse(t, "x"))
+			if err == nil {
+				t.Fatalf("expected error for unbound x after Reset, got nil")
+			}
+		})
+	}
+}
+
+func TestEngineErrorStages(t *testing.T) {
+	testCases := []struct {
+		engine   string
+		input    string
+		expected Stage
+	}{
+		{EvalEngine, "1 + true", RuntimeStage},
+		{EvalEngine, "y", RuntimeStage},
+		{VMEngine, "1 + true", RuntimeStage},
+		{VMEngine, "y", CompileStage},
+	}
+
+	for _, tc := range testCases {
+		eng, err := New(tc.engine)
+		if err != nil {
+			t.Fatalf("New(%q) error: %s", tc.engine, err)
+		}
+
+		_, err = eng.Run(parse(t, tc.input))
+		if err == nil {
+			t.Fatalf("%s: Run(%q) expected error, got nil", tc.engine, tc.input)
+		}
+
+		if StageOf(err) != tc.expected {
+			t.Errorf("%s: StageOf(%q) = %q, want = %q", tc.engine, err, StageOf(err), tc.expected)
+		}
+	}
+}
+
+func TestNewUnknownEngine(t *testing.T) {
+	_, err := New("jit")
+	if err == nil {
+		t.Fatalf("expected error for unknown engine, got nil")
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	l := lexer.NewLexer(input)
+	p := parser.NewParser(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
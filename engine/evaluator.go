@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"BigTalk_Interpreter/ast"
+	"BigTalk_Interpreter/evaluator"
+	"BigTalk_Interpreter/object"
+	"errors"
+)
+
+// Evaluator runs programs with the tree-walking evaluator.
+type Evaluator struct {
+	env *object.Environment
+}
+
+func NewEvaluator() *Evaluator {
+	return &Evaluator{env: object.NewEnvironment()}
+}
+
+func (e *Evaluator) Name() string {
+	return EvalEngine
+}
+
+func (e *Evaluator) Run(program *ast.Program) (object.IObject, error) {
+	result := evaluator.Eval(program, e.env)
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, &Error{Stage: RuntimeStage, Err: errors.New(errObj.Message)}
+	}
+	return result, nil
+}
+
+func (e *Evaluator) Reset() {
+	e.env = object.NewEnvironment()
+}
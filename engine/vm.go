@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"BigTalk_Interpreter/ast"
+	"BigTalk_Interpreter/compiler"
+	"BigTalk_Interpreter/object"
+	"BigTalk_Interpreter/vm"
+)
+
+// VM compiles programs to bytecode and runs them on the virtual machine.
+// The symbol table, constant pool and globals are carried over between runs.
+type VM struct {
+	symbolTable *compiler.SymbolTable
+	constants   []object.IObject
+	globals     []object.IObject
+}
+
+func NewVM() *VM {
+	v := &VM{}
+	v.Reset()
+	return v
+}
+
+func (v *VM) Name() string {
+	return VMEngine
+}
+
+func (v *VM) Run(program *ast.Program) (object.IObject, error) {
+	comp := compiler.NewCompilerWithState(v.symbolTable, v.constants)
+	err := comp.Compile(program)
+	if err != nil {
+		return nil, &Error{Stage: CompileStage, Err: err}
+	}
+
+	bytecode := comp.ByteCode()
+	v.constants = bytecode.Constants
+
+	vMachine := vm.NewVirtualMachineWithGlobalStore(bytecode, v.globals)
+	err = vMachine.Run()
+	if err != nil {
+		return nil, &Error{Stage: RuntimeStage, Err: err}
+	}
+	return vMachine.LastPoppedStackElement(), nil
+}
+
+// Compile compiles the program against the session state without running it and without
+// keeping any of the definitions it makes.
+func (v *VM) Compile(program *ast.Program) (*compiler.ByteCode, error) {
+	constants := make([]object.IObject, len(v.constants))
+	copy(constants, v.constants)
+
+	comp := compiler.NewCompilerWithState(v.symbolTable.Copy(), constants)
+	err := comp.Compile(program)
+	if err != nil {
+		return nil, &Error{Stage: CompileStage, Err: err}
+	}
+	return comp.ByteCode(), nil
+}
+
+func (v *VM) SymbolTable() *compiler.SymbolTable {
+	return v.symbolTable
+}
+
+func (v *VM) Constants() []object.IObject {
+	return v.constants
+}
+
+func (v *VM) Globals() []object.IObject {
+	return v.globals
+}
+
+func (v *VM) Reset() {
+	v.symbolTable = compiler.NewSymbolTable()
+	for i, fn := range object.BuiltinFunctions {
+		v.symbolTable.DefineBuiltin(i, fn.Name)
+	}
+	v.constants = []object.IObject{}
+	v.globals = make([]object.IObject, vm.GlobalsSize)
+}
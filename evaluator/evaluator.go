@@ -4,12 +4,17 @@ import (
 	"BigTalk_Interpreter/ast"
 	"BigTalk_Interpreter/object"
 	"fmt"
+	"math"
+	"strings"
 )
 
 var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 // Eval evaluates the abstract syntax tree (AST) node and returns its computed value or an error.
@@ -19,6 +24,8 @@ func Eval(node ast.INode, env *object.Environment) object.IObject {
 		return evalProgram(node, env)
 	case *ast.ExpressionStatement:
 		return Eval(node.Value, env)
+	case *ast.NullLiteral:
+		return NULL
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.PrefixExpression:
@@ -28,6 +35,10 @@ func Eval(node ast.INode, env *object.Environment) object.IObject {
 		}
 		return evalPrefixExpression(node.Operator, right)
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
+
 		left := Eval(node.LeftValue, env)
 		if isError(left) {
 			return left
@@ -38,30 +49,55 @@ func Eval(node ast.INode, env *object.Environment) object.IObject {
 			return right
 		}
 		return evalInfixExpression(node.Operator, left, right)
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
 	case *ast.ReturnStatement:
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return &object.Error{Message: object.ErrorMessage(val), Thrown: val}
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
+		if node.Pattern != nil {
+			return destructure(node.Pattern, val, env)
+		}
 		env.Set(node.Name.Value, val)
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		return &object.Function{Name: node.Name, Parameters: params, Defaults: node.Defaults, Rest: node.Rest, Body: body, Env: env}
 	case *ast.CallExpression:
 		function := Eval(node.Func, env)
 		if isError(function) {
@@ -75,6 +111,12 @@ func Eval(node ast.INode, env *object.Environment) object.IObject {
 		return applyFunction(function, args)
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
+	case *ast.InterpolatedString:
+		parts := evalExpressions(node.Parts, env)
+		if len(parts) == 1 && isError(parts[0]) {
+			return parts[0]
+		}
+		return interpolate(parts)
 	case *ast.ArrayLiteral:
 		items := evalExpressions(node.Items, env)
 		if len(items) == 1 && isError(items[0]) {
@@ -92,6 +134,8 @@ func Eval(node ast.INode, env *object.Environment) object.IObject {
 			return index
 		}
 		return evalIndexExpression(left, index)
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
 	case *ast.MapLiteral:
 		return evalMapLiteral(node, env)
 	}
@@ -144,18 +188,21 @@ func evalBangOperatorExpression(right object.IObject) object.IObject {
 }
 
 func evalMinusPrefixOperatorExpression(right object.IObject) object.IObject {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 // evalInfixExpression evaluates the given infix expression node and returns the result of the computation.
-// It supports various operators including arithmetic and comparison operators for integers and string concatenation for strings.
+// It supports various operators including arithmetic and comparison operators for integers and floats and string concatenation for strings.
+// An integer operand combined with a float is promoted to float.
 // If the operator and operands have incompatible types, it returns an error.
-// Supported operator: "+", "-", "*", "/", "==", "!=", "<", ">", "+"
+// Supported operator: "+", "-", "*", "/", "%", "==", "!=", "<", "<=", ">", ">="
 // Parameters: operator (string) - the operator to evaluate
 //
 //	left (object.IObject) - the left operand
@@ -166,24 +213,126 @@ func evalInfixExpression(operator string, left, right object.IObject) object.IOb
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
 	case operator == "!=":
 		return nativeBoolToBooleanObject(left != right)
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
-	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
-		return evalStringInfixExpression(operator, left, right)
-
 	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
+// evalAssignExpression stores the value in the binding the name currently resolves to, so a
+// closure assigning to a variable of its defining scope updates it for everyone. A compound
+// assignment reads the binding before evaluating the right hand side.
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.IObject {
+	if target, ok := node.Target.(*ast.IndexExpression); ok {
+		return evalIndexAssignment(node, target, env)
+	}
+	name := node.Target.(*ast.Identifier).Value
+
+	current, ok := env.Get(name)
+	if !ok {
+		return newError("identifier not found: " + name)
+	}
+
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	if node.Operator != "=" {
+		val = evalInfixExpression(strings.TrimSuffix(node.Operator, "="), current, val)
+		if isError(val) {
+			return val
+		}
+	}
+
+	env.Assign(name, val)
+	return val
+}
+
+// evalIndexAssignment evaluates `left[index] = value`, left and index are evaluated once
+// even for a compound assignment.
+func evalIndexAssignment(node *ast.AssignExpression, target *ast.IndexExpression, env *object.Environment) object.IObject {
+	left := Eval(target.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	index := Eval(target.Index, env)
+	if isError(index) {
+		return index
+	}
+
+	var current object.IObject
+	if node.Operator != "=" {
+		current = evalIndexExpression(left, index)
+		if isError(current) {
+			return current
+		}
+	}
+
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	if node.Operator != "=" {
+		val = evalInfixExpression(strings.TrimSuffix(node.Operator, "="), current, val)
+		if isError(val) {
+			return val
+		}
+	}
+	return evalSetIndex(left, index, val)
+}
+
+// evalSetIndex mirrors OpSetIndex: arrays and maps are mutated in place, so every binding
+// holding the same object sees the change.
+func evalSetIndex(left, index, val object.IObject) object.IObject {
+	switch left := left.(type) {
+	case *object.Array:
+		i, ok := index.(*object.Integer)
+		if !ok {
+			return newError("array index must be INTEGER, got %s", index.Type())
+		}
+		if i.Value < 0 || i.Value >= int64(len(left.Items)) {
+			return newError("index out of range: %d (array length %d)", i.Value, len(left.Items))
+		}
+		left.Items[i.Value] = val
+	case *object.Map:
+		key, ok := index.(object.IHashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		left.Pairs[key.HashKey()] = object.MapPair{Key: index, Value: val}
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+	return val
+}
+
+// evalIntegerInfixExpression mirrors the VM: division and modulo truncate toward zero and
+// a zero divisor is an error.
 func evalIntegerInfixExpression(operator string, left, right object.IObject) object.IObject {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 
+	if rightVal == 0 {
+		switch operator {
+		case "/":
+			return newError("division by zero")
+		case "%":
+			return newError("modulo by zero")
+		}
+	}
+
 	switch operator {
 	case "+":
 		return &object.Integer{Value: leftVal + rightVal}
@@ -193,10 +342,16 @@ func evalIntegerInfixExpression(operator string, left, right object.IObject) obj
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
 		return &object.Integer{Value: leftVal / rightVal}
+	case "%":
+		return &object.Integer{Value: leftVal % rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -206,14 +361,79 @@ func evalIntegerInfixExpression(operator string, left, right object.IObject) obj
 	}
 }
 
-func evalStringInfixExpression(operator string, left, right object.IObject) object.IObject {
-	if operator != "+" {
+func evalFloatInfixExpression(operator string, left, right object.IObject) object.IObject {
+	leftVal, _ := object.ToFloat(left)
+	rightVal, _ := object.ToFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
+}
+
+// evalLogicalExpression evaluates `&&` and `||`. The right operand is only evaluated when
+// the left one does not decide the result, which is always a boolean.
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.IObject {
+	left := Eval(node.LeftValue, env)
+	if isError(left) {
+		return left
+	}
 
+	if node.Operator == "&&" && !isTruthy(left) {
+		return FALSE
+	}
+	if node.Operator == "||" && isTruthy(left) {
+		return TRUE
+	}
+
+	right := Eval(node.RightValue, env)
+	if isError(right) {
+		return right
+	}
+	return nativeBoolToBooleanObject(isTruthy(right))
+}
+
+func isNumber(obj object.IObject) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+// evalStringInfixExpression concatenates strings and compares them by their contents.
+func evalStringInfixExpression(operator string, left, right object.IObject) object.IObject {
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
-	return &object.String{Value: leftVal + rightVal}
+
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.IObject {
@@ -222,13 +442,37 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.IObj
 		return condition
 	}
 
+	var result object.IObject
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
-	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		result = Eval(ie.Consequence, env)
 	} else {
+		result = evalElseBranches(ie, env)
+	}
+
+	// Like in the VM a branch without a trailing expression evaluates to null.
+	if result == nil {
 		return NULL
 	}
+	return result
+}
+
+// evalElseBranches evaluates the consequence of the first else-if clause whose condition is
+// truthy, or the else block when there is none.
+func evalElseBranches(ie *ast.IfExpression, env *object.Environment) object.IObject {
+	for _, elseIf := range ie.ElseIfs {
+		condition := Eval(elseIf.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return Eval(elseIf.Consequence, env)
+		}
+	}
+
+	if ie.Alternative != nil {
+		return Eval(ie.Alternative, env)
+	}
+	return nil
 }
 
 func isTruthy(obj object.IObject) bool {
@@ -250,8 +494,8 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 		result = Eval(statement, env)
 
 		if result != nil {
-			resultType := result.Type()
-			if resultType == object.RETURN_VALUE_OBJ || resultType == object.ERROR_OBJ {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
 				return result
 			}
 		}
@@ -259,6 +503,74 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
+// evalWhileStatement runs the body for as long as the condition is truthy. Loops are
+// statements and produce no value.
+func evalWhileStatement(node *ast.WhileStatement, env *object.Environment) object.IObject {
+	for {
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return nil
+		}
+
+		result, done := evalLoopBody(node.Body, env)
+		if done {
+			return result
+		}
+	}
+}
+
+func evalForStatement(node *ast.ForStatement, env *object.Environment) object.IObject {
+	if node.Init != nil {
+		if init := Eval(node.Init, env); isError(init) {
+			return init
+		}
+	}
+
+	for {
+		if node.Condition != nil {
+			condition := Eval(node.Condition, env)
+			if isError(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				return nil
+			}
+		}
+
+		result, done := evalLoopBody(node.Body, env)
+		if done {
+			return result
+		}
+
+		if node.Post != nil {
+			if post := Eval(node.Post, env); isError(post) {
+				return post
+			}
+		}
+	}
+}
+
+// evalLoopBody runs one iteration. done is set when the loop has to stop, result is then
+// the return value or error to hand to the caller, or nil after a `break`.
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) (result object.IObject, done bool) {
+	result = evalBlockStatement(body, env)
+	if result == nil {
+		return nil, false
+	}
+
+	switch result.Type() {
+	case object.BREAK_OBJ:
+		return nil, true
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.IObject {
 	if val, ok := env.Get(node.Value); ok {
 		return val
@@ -274,16 +586,225 @@ func evalExpressions(exps []ast.IExpression, env *object.Environment) []object.I
 	var results []object.IObject
 
 	for _, exp := range exps {
+		spread, isSpread := exp.(*ast.SpreadElement)
+		if isSpread {
+			exp = spread.Value
+		}
+
 		evaluated := Eval(exp, env)
 		if isError(evaluated) {
 			return []object.IObject{evaluated}
 		}
-		results = append(results, evaluated)
+		if !isSpread {
+			results = append(results, evaluated)
+			continue
+		}
+
+		array, ok := evaluated.(*object.Array)
+		if !ok {
+			return []object.IObject{newError("spread operand must be ARRAY, got %s", evaluated.Type())}
+		}
+		results = append(results, array.Items...)
 	}
 
 	return results
 }
 
+// evalTryExpression evaluates the try block, and the catch block with the error value bound
+// to its parameter when the try block raises an error. The catch block runs in an environment
+// of its own, so its parameter does not replace a binding outside of it. The finally block
+// runs either way, an error, return, break or continue in it takes the place of the result.
+func evalTryExpression(node *ast.TryExpression, env *object.Environment) object.IObject {
+	result := Eval(node.Block, env)
+	if err, ok := result.(*object.Error); ok && node.Catch != nil {
+		catchEnv := object.NewWrappedEnvironment(env)
+		if node.CatchParam != nil {
+			catchEnv.Set(node.CatchParam.Value, errorValue(err))
+		}
+		result = Eval(node.Catch, catchEnv)
+	}
+
+	if node.Finally != nil {
+		switch finally := Eval(node.Finally, env).(type) {
+		case *object.Error, *object.ReturnValue, *object.Break, *object.Continue:
+			return finally
+		}
+	}
+
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+// errorValue returns the value a catch block receives for err.
+func errorValue(err *object.Error) object.IObject {
+	if err.Thrown != nil {
+		return object.ThrownErrorValue(err.Thrown, err.Stack)
+	}
+	return object.NewErrorValue(object.RuntimeErrorKind, err.Message, err.Stack)
+}
+
+// evalMatchExpression evaluates the body of the first arm whose pattern matches the subject
+// and whose guard holds, or returns null when no arm does. Every arm gets an environment of
+// its own, so the names its pattern binds and its body defines do not outlive the arm.
+func evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.IObject {
+	subject := Eval(node.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range node.Arms {
+		armEnv := object.NewWrappedEnvironment(env)
+		matched, err := matchPattern(arm.Pattern, subject, armEnv)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		result := Eval(arm.Body, armEnv)
+		if result == nil {
+			return NULL
+		}
+		return result
+	}
+	return NULL
+}
+
+// matchPattern reports whether value matches pattern and binds the names the pattern
+// introduces, in the same order as the compiled tests do.
+func matchPattern(pattern ast.IExpression, value object.IObject, env *object.Environment) (bool, object.IObject) {
+	if ast.IsWildcard(pattern) {
+		return true, nil
+	}
+
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		env.Set(pattern.Value, value)
+		return true, nil
+	case *ast.ArrayPattern:
+		array, ok := value.(*object.Array)
+		count := len(pattern.Elements)
+		if !ok || len(array.Items) < count || pattern.Rest == nil && len(array.Items) != count {
+			return false, nil
+		}
+
+		for i, element := range pattern.Elements {
+			matched, err := matchPattern(element.Target, array.Items[i], env)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+
+		if pattern.Rest != nil && !ast.IsWildcard(pattern.Rest) {
+			env.Set(pattern.Rest.Value, evalSlice(array, &object.Integer{Value: int64(count)}, NULL))
+		}
+		return true, nil
+	case *ast.MapPattern:
+		hash, ok := value.(*object.Map)
+		if !ok {
+			return false, nil
+		}
+
+		items := make([]object.IObject, len(pattern.Elements))
+		for i, element := range pattern.Elements {
+			pair, ok := hash.Pairs[(&object.String{Value: element.Key.Value}).HashKey()]
+			if !ok {
+				return false, nil
+			}
+			items[i] = pair.Value
+		}
+
+		for i, element := range pattern.Elements {
+			matched, err := matchPattern(element.Target, items[i], env)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	default:
+		literal := Eval(pattern, env)
+		if isError(literal) {
+			return false, literal
+		}
+		return evalInfixExpression("==", value, literal) == TRUE, nil
+	}
+}
+
+// destructure binds the elements of an array or map pattern to the parts of value, the same
+// way the compiler does it by indexing value. It returns an error or nil.
+func destructure(pattern ast.IExpression, value object.IObject, env *object.Environment) object.IObject {
+	switch pattern := pattern.(type) {
+	case *ast.ArrayPattern:
+		for i, element := range pattern.Elements {
+			item := evalIndexExpression(value, &object.Integer{Value: int64(i)})
+			err := bindPatternElement(element, item, env)
+			if err != nil {
+				return err
+			}
+		}
+
+		if pattern.Rest != nil {
+			rest := evalSlice(value, &object.Integer{Value: int64(len(pattern.Elements))}, NULL)
+			if isError(rest) {
+				return rest
+			}
+			env.Set(pattern.Rest.Value, rest)
+		}
+	case *ast.MapPattern:
+		for _, element := range pattern.Elements {
+			item := evalIndexExpression(value, &object.String{Value: element.Key.Value})
+			err := bindPatternElement(element, item, env)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bindPatternElement binds the target of element to value, or to the default of the element
+// when value is null.
+func bindPatternElement(element *ast.PatternElement, value object.IObject, env *object.Environment) object.IObject {
+	if isError(value) {
+		return value
+	}
+	if value == NULL && element.Default != nil {
+		value = Eval(element.Default, env)
+		if isError(value) {
+			return value
+		}
+	}
+
+	if ident, ok := element.Target.(*ast.Identifier); ok {
+		env.Set(ident.Value, value)
+		return nil
+	}
+	return destructure(element.Target, value, env)
+}
+
+// interpolate joins the string forms of the evaluated parts of an interpolated string.
+func interpolate(parts []object.IObject) object.IObject {
+	var out strings.Builder
+
+	for _, part := range parts {
+		out.WriteString(part.Inspect())
+	}
+	return &object.String{Value: out.String()}
+}
+
 func newError(format string, a ...any) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
@@ -307,8 +828,14 @@ func isError(obj object.IObject) bool {
 func applyFunction(fn object.IObject, args []object.IObject) object.IObject {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendedFunctionEnv(fn, args)
+		extendedEnv, err := extendedFunctionEnv(fn, args)
+		if err != nil {
+			return err
+		}
 		evaluated := Eval(fn.Body, extendedEnv)
+		if err, ok := evaluated.(*object.Error); ok {
+			err.Stack = append(err.Stack, functionName(fn.Name))
+		}
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
 		if result := fn.Fn(args...); result != nil {
@@ -320,13 +847,44 @@ func applyFunction(fn object.IObject, args []object.IObject) object.IObject {
 	}
 }
 
-func extendedFunctionEnv(fn *object.Function, args []object.IObject) *object.Environment {
-	env := object.NewWrappedEnvironment(fn.Env)
+// extendedFunctionEnv binds the arguments to the parameters of fn in a new environment
+// enclosed by the one fn was defined in. A parameter left out by the caller gets its default
+// value, evaluated in the new environment so it can refer to the parameters before it. A
+// rest parameter gets a new array of the arguments left over.
+func extendedFunctionEnv(fn *object.Function, args []object.IObject) (*object.Environment, object.IObject) {
+	optional := 0
+	for _, value := range fn.Defaults {
+		if value != nil {
+			optional++
+		}
+	}
+	err := object.CheckArity(len(args), len(fn.Parameters), optional, fn.Rest != nil)
+	if err != nil {
+		return nil, newError("%s", err)
+	}
 
+	env := object.NewWrappedEnvironment(fn.Env)
 	for i, param := range fn.Parameters {
-		env.Set(param.Value, args[i])
+		if i < len(args) {
+			env.Set(param.Value, args[i])
+			continue
+		}
+
+		value := Eval(fn.Defaults[i], env)
+		if isError(value) {
+			return nil, value
+		}
+		env.Set(param.Value, value)
+	}
+
+	if fn.Rest != nil {
+		rest := []object.IObject{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &object.Array{Items: rest})
 	}
-	return env
+	return env, nil
 }
 
 func unwrapReturnValue(obj object.IObject) object.IObject {
@@ -340,6 +898,8 @@ func evalIndexExpression(left, index object.IObject) object.IObject {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.MAP_OBJ:
 		return evalMapIndexExpression(left, index)
 	default:
@@ -359,6 +919,66 @@ func evalArrayIndexExpression(array, index object.IObject) object.IObject {
 	return arrayObj.Items[idx]
 }
 
+// evalStringIndexExpression returns the code point at the index as a string of its own, or
+// NULL when the index is out of range.
+func evalStringIndexExpression(str, index object.IObject) object.IObject {
+	runes := []rune(str.(*object.String).Value)
+	idx := index.(*object.Integer).Value
+
+	if idx < 0 || idx >= int64(len(runes)) {
+		return NULL
+	}
+
+	return &object.String{Value: string(runes[idx])}
+}
+
+// evalSliceExpression returns the part of a string or array from the low bound up to, but
+// not including, the high bound. Strings are sliced by code points and arrays are copied.
+// A left out or null bound stands for the start or the end, bounds out of range are clamped
+// to it and a low bound past the high bound gives an empty result.
+func evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.IObject {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	bounds := [2]object.IObject{NULL, NULL}
+	for i, bound := range []ast.IExpression{node.Low, node.High} {
+		if bound == nil {
+			continue
+		}
+		bounds[i] = Eval(bound, env)
+		if isError(bounds[i]) {
+			return bounds[i]
+		}
+	}
+	return evalSlice(left, bounds[0], bounds[1])
+}
+
+// evalSlice returns the part of a string or an array from the low bound up to, but not
+// including, the high bound. A null bound stands for the start or the end.
+func evalSlice(left, low, high object.IObject) object.IObject {
+	switch left := left.(type) {
+	case *object.String:
+		runes := []rune(left.Value)
+		start, end, err := object.SliceBounds(low, high, len(runes))
+		if err != nil {
+			return newError("%s", err)
+		}
+		return &object.String{Value: string(runes[start:end])}
+	case *object.Array:
+		start, end, err := object.SliceBounds(low, high, len(left.Items))
+		if err != nil {
+			return newError("%s", err)
+		}
+		items := make([]object.IObject, end-start)
+		copy(items, left.Items[start:end])
+		return &object.Array{Items: items}
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+}
+
 // evalMapLiteral evaluates the map literal AST node and returns a Map object.
 // It iterates over the key-value pairs in the node and evaluates each key and value.
 // If there is an error during evaluation, it returns the error.
@@ -406,3 +1026,11 @@ func evalMapIndexExpression(hashMap, index object.IObject) object.IObject {
 
 	return pair.Value
 }
+
+// functionName names a function in the stack of an error value the same way the VM does.
+func functionName(name string) string {
+	if name == "" {
+		return "<anonymous>"
+	}
+	return name
+}
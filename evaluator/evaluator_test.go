@@ -5,9 +5,530 @@ import (
 	"BigTalk_Interpreter/object"
 	"BigTalk_Interpreter/parser"
 	"fmt"
+	"math"
 	"testing"
 )
 
+func TestEvalTryExpression(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"try { 1 } catch (e) { 2 }", 1},
+		{"try { 1 / 0 } catch (e) { e[\"message\"] }", "division by zero"},
+		{"try { 1 / 0 } catch (e) { e[\"kind\"] }", "RuntimeError"},
+		{"try { len(1) } catch (e) { e[\"message\"] }", "argument to `len` not supported, got INTEGER"},
+		{`try { throw "boom" } catch (e) { e["kind"] + ": " + e["message"] }`, "Error: boom"},
+		{`try { throw {"kind": "Custom", "code": 7} } catch (e) { e["kind"] + "${e["code"]}" }`, "Custom7"},
+		{`try { throw {"message": "m"} } catch (e) { e["kind"] }`, "Error"},
+		{"try { throw [1, 2] } catch { 5 }", 5},
+		{"try { } catch (e) { 1 }", nil},
+		{"try { throw 1 } catch (e) { len(e[\"stack\"]) }", 0},
+		{"let f = fn() { throw 1 }; let g = fn() { f() }; try { g() } catch (e) { e[\"stack\"][0] + e[\"stack\"][1] }", "fg"},
+		{"let f = fn(n) { if (n == 0) { throw 0 } f(n - 1) }; try { f(3) } catch (e) { len(e[\"stack\"]) }", 4},
+		{"let f = fn() { fn() { 1 / 0 }() }; try { f() } catch (e) { e[\"stack\"][0] }", "<anonymous>"},
+		{"let f = fn() { try { throw 1 } catch (e) { throw e[\"message\"] + \"2\" } }; try { f() } catch (e) { e[\"message\"] }", "12"},
+		{"let f = fn() { try { throw {\"kind\": \"K\"} } catch (e) { throw e } }; try { f() } catch (e) { len(e[\"stack\"]) }", 0},
+		{"[1, try { [2, fn() { throw 0 }()] } catch (e) { 3 }, 4]", []int{1, 3, 4}},
+		{"let f = fn() { try { throw 1 } catch (e) { fn() { e[\"message\"] } } }; f()()", "1"},
+		{"let x = 0; try { x = 1 } finally { x = x + 10 }; x", 11},
+		{"let x = 0; try { try { throw 1 } finally { x = 5 } } catch (e) { x * 2 }", 10},
+		{"let x = 0; try { try { throw 1 } catch (e) { throw 2 } finally { x = 5 } } catch (e) { \"${x}\" + e[\"message\"] }", "52"},
+		{"let x = 0; let f = fn() { try { return 1 } finally { x = 7 } }; f() + x", 8},
+		{"let f = fn() { try { return 1 } finally { return 2 } }; f()", 2},
+		{"let f = fn() { try { throw 1 } finally { return 2 } }; f()", 2},
+		{"let log = []; for (let i = 0; i < 4; i += 1) { try { if (i == 1) { continue } if (i == 2) { break } log = push(log, i) } finally { log = push(log, i * 10) } }; log", []int{0, 0, 10, 20}},
+		{"let f = fn() { let i = 0; while (true) { try { i += 1; if (i == 3) { return i } } catch (e) { } } }; f()", 3},
+		{`match (try { throw {"kind": "NotFound"} } catch (e) { e }) { {"kind": "NotFound"} => 1, _ => 2 }`, 1},
+	}
+
+	for _, tc := range testCases {
+		evaluated := setupEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("%s: evaluated = %+v, want %q", tc.input, evaluated, expected)
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok || len(array.Items) != len(expected) {
+				t.Errorf("%s: evaluated = %+v, want %d items", tc.input, evaluated, len(expected))
+				continue
+			}
+			for i, item := range expected {
+				testIntegerObject(t, array.Items[i], int64(item))
+			}
+		}
+	}
+
+	errorCases := []evalErrorTestCase{
+		{`throw "boom"`, "boom"},
+		{`throw {"kind": "K", "message": "m"}`, "m"},
+		{`try { 1 / 0 } finally { 2 }`, "division by zero"},
+		{`try { throw 1 } catch (e) { throw 2 }`, "2"},
+	}
+	runEvalErrorTests(t, errorCases)
+}
+
+func TestEvalMatchExpression(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"match (0) { 0 => 1, _ => 2 }", 1},
+		{"match (-1) { 1 => 1, -1 => 2 }", 2},
+		{"match (2) { 2.0 => 1, _ => 2 }", 1},
+		{`match ("a" + "b") { "ab" => 1, _ => 2 }`, 1},
+		{"match (null) { false => 1, null => 2 }", 2},
+		{"match (5) { 1 => 1 }", nil},
+		{"match (5) { n => n * 2 }", 10},
+		{"match (5) { _ => 3 }", 3},
+		{"match ([]) { [] => 1, _ => 2 }", 1},
+		{"match ([1, 2]) { [x] => x, [x, y] => x + y }", 3},
+		{"match ([1, 2, 3]) { [x, ...rest] => rest }", []int{2, 3}},
+		{"match ([1]) { [x, ...rest] => len(rest) }", 0},
+		{"match ([1, 2, 3]) { [_, _] => 1, [_, ..._] => 2 }", 2},
+		{"match ([[1, 2], [3]]) { [[a, b], [c]] => [a, b, c] }", []int{1, 2, 3}},
+		{"match ([1, [2]]) { [1, [3]] => 1, [1, [x]] => x }", 2},
+		{"match ([1, 2]) { [x, y] if x > y => 1, [x, y] if x < y => 2 }", 2},
+		{"match (\"[1]\") { [x] => 1, _ => 2 }", 2},
+		{`match ({"type": "circle", "r": 2}) { {"type": "square"} => 1, {"type": "circle", "r": r} => r }`, 2},
+		{`match ({"a": null}) { {"b": _} => 1, {"a": x} => 2 }`, 2},
+		{`match ({"a": 1}) { {} => 1 }`, 1},
+		{`match ([1]) { {} => 1, _ => 2 }`, 2},
+		{`match ({"p": [1, 2]}) { {p: [x, y]} => x + y }`, 3},
+		{`match ({"n": 4}) { {n} if n > 5 => 1, {n} => n }`, 4},
+		{"match (3) { x => { let y = x + 1; y * 2 } }", 8},
+		{"match (3) { x => { } }", nil},
+		{"let f = fn(v) { match (v) { 0 => 1, n => n * f(n - 1) } }; f(5)", 120},
+		{"match (match (1) { 1 => [2] }) { [x] => match (x) { 2 => 3 } }", 3},
+		{"let f = fn(v) { match (v) { [x] => fn() { x } } }; f([7])()", 7},
+		{"match (1) { 1 => 10 } + match (2) { 2 => 20 }", 30},
+	}
+
+	for _, tc := range testCases {
+		evaluated := setupEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok || len(array.Items) != len(expected) {
+				t.Errorf("%s: evaluated = %+v, want %d items", tc.input, evaluated, len(expected))
+				continue
+			}
+			for i, item := range expected {
+				testIntegerObject(t, array.Items[i], int64(item))
+			}
+		}
+	}
+
+	runEvalErrorTests(t, []evalErrorTestCase{{"match (1) { x if y => 1 }", "identifier not found: y"}})
+}
+
+func TestEvalDestructuringLet(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"let [a, b] = [1]; b", nil},
+		{"let [a, b = a + 1, c = 5] = [1]; [a, b, c]", []int{1, 2, 5}},
+		{"let [a = 9] = [null]; a", 9},
+		{"let [head, ...tail] = [1, 2, 3]; tail", []int{2, 3}},
+		{"let [head, ...tail] = []; tail", []int{}},
+		{"let [[a, b], [c]] = [[1, 2], [3]]; [a, b, c]", []int{1, 2, 3}},
+		{"let [[a, b] = [4, 5]] = []; [a, b]", []int{4, 5}},
+		{`let {title, artist} = {"title": 1, "artist": 2}; [title, artist]`, []int{1, 2}},
+		{`let {x: renamed, y = 7} = {"x": 3}; [renamed, y]`, []int{3, 7}},
+		{`let {"a b": c} = {"a b": 4}; c`, 4},
+		{`let {inner: {v}, list: [first, ...more]} = {"inner": {"v": 1}, "list": [2, 3, 4]}; [v, first, len(more)]`, []int{1, 2, 2}},
+		{"let f = fn(pair) { let [k, v = k * 2] = pair; k + v }; f([3])", 9},
+		{"let x = 1; let [x, y = x] = [5]; y", 5},
+		{"let [a = if (true) { let [z] = [8]; z }, b] = [null, 2]; [a, b]", []int{8, 2}},
+	}
+
+	for _, tc := range testCases {
+		evaluated := setupEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok || len(array.Items) != len(expected) {
+				t.Errorf("%s: evaluated = %+v, want %d items", tc.input, evaluated, len(expected))
+				continue
+			}
+			for i, item := range expected {
+				testIntegerObject(t, array.Items[i], int64(item))
+			}
+		}
+	}
+
+	errorCases := []evalErrorTestCase{
+		{"let [a] = 5;", "index operator not supported: INTEGER"},
+		{`let {a} = [1];`, "index operator not supported: ARRAY"},
+		{"let [a = 1 / 0] = [];", "division by zero"},
+		{"let [a, ...r] = {};", "slice operator not supported: HASH"},
+	}
+	runEvalErrorTests(t, errorCases)
+}
+
+func TestEvalSpread(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"let a = [1, 2]; [0, ...a, 3]", []int{0, 1, 2, 3}},
+		{"let a = [1, 2]; [...a, ...a]", []int{1, 2, 1, 2}},
+		{"[...[], ...[]]", []int{}},
+		{"let a = [1, 2]; let b = [...a]; b[0] = 9; a", []int{1, 2}},
+		{"let add = fn(x, y) { x + y }; add(...[3, 4])", 7},
+		{"let add = fn(x, y) { x + y }; add(3, ...[4])", 7},
+		{"let f = fn(a, ...rest) { rest }; f(...[1, 2], 3, ...[4])", []int{2, 3, 4}},
+		{"len(...[[1, 2, 3]])", 3},
+		{"let f = fn(a, b = 5) { a + b }; f(...[1])", 6},
+	}
+
+	for _, tc := range testCases {
+		evaluated := setupEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok || len(array.Items) != len(expected) {
+				t.Errorf("%s: evaluated = %+v, want %d items", tc.input, evaluated, len(expected))
+				continue
+			}
+			for i, item := range expected {
+				testIntegerObject(t, array.Items[i], int64(item))
+			}
+		}
+	}
+
+	errorCases := []evalErrorTestCase{
+		{`[..."abc"]`, "spread operand must be ARRAY, got STRING"},
+		{"let f = fn(x) { x }; f(...{})", "spread operand must be ARRAY, got HASH"},
+		{"fn(a, b) { a }(...[1, 2, 3])", "wrong number of arguments: got = 3, want = 2"},
+		{"[...[1], ...x]", "identifier not found: x"},
+	}
+	runEvalErrorTests(t, errorCases)
+}
+
+func TestEvalDefaultAndRestParameters(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"let f = fn(a, b = 2) { a * b }; f(5)", 10},
+		{"let f = fn(a, b = 2) { a * b }; f(5, 3)", 15},
+		{"let f = fn(a, b = a + 1, c = b * 2) { [a, b, c] }; f(1)", []int{1, 2, 4}},
+		{"let f = fn(a, b = a + 1, c = b * 2) { [a, b, c] }; f(1, 5)", []int{1, 5, 10}},
+		{"let b = 7; let f = fn(a = b, b = 1) { a }; f()", 7},
+		{"let f = fn(a = []) { push(a, 1) }; f(); len(f())", 1},
+		{"let f = fn(first, ...rest) { rest }; f(1, 2, 3)", []int{2, 3}},
+		{"let f = fn(first, ...rest) { len(rest) }; f(1)", 0},
+		{"let f = fn(...all) { all }; f()", []int{}},
+		{"let f = fn(a, b = 10, ...rest) { [a, b, len(rest)] }; f(1, 2, 3, 4)", []int{1, 2, 2}},
+		{"let f = fn(a, b = 10, ...rest) { [a, b, len(rest)] }; f(1)", []int{1, 10, 0}},
+		{"let outer = fn(k) { fn(v = k) { v } }; outer(4)()", 4},
+		{"let sum = fn(n, acc = 0) { if (n == 0) { acc } else { sum(n - 1, acc + n) } }; sum(4)", 10},
+	}
+
+	for _, tc := range testCases {
+		evaluated := setupEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok || len(array.Items) != len(expected) {
+				t.Errorf("%s: evaluated = %+v, want %d items", tc.input, evaluated, len(expected))
+				continue
+			}
+			for i, item := range expected {
+				testIntegerObject(t, array.Items[i], int64(item))
+			}
+		}
+	}
+
+	errorCases := []evalErrorTestCase{
+		{"fn() { 1 }(1)", "wrong number of arguments: got = 1, want = 0"},
+		{"fn(a) { a }()", "wrong number of arguments: got = 0, want = 1"},
+		{"fn(a, b = 2) { a }(1, 2, 3)", "wrong number of arguments: got = 3, want 1 to 2"},
+		{"fn(a, ...rest) { a }()", "wrong number of arguments: got = 0, want at least 1"},
+		{"fn(a, b = a / 0) { a }(1)", "division by zero"},
+	}
+	runEvalErrorTests(t, errorCases)
+}
+
+func TestEvalElseIfAndNull(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"if (false) { 1 } else if (true) { 2 } else { 3 }", 2},
+		{"if (false) { 1 } else if (false) { 2 } else { 3 }", 3},
+		{"if (false) { 1 } else if (false) { 2 }", nil},
+		{"if (true) { 1 } else if (1 / 0) { 2 }", 1},
+		{"let n = 75; if (n >= 90) { 1 } else if (n >= 80) { 2 } else if (n >= 70) { 3 } else { 4 }", 3},
+		{"if (false) { 1 } else if (true) { let x = 1; }", nil},
+		{"null", nil},
+		{"let x = null; x", nil},
+		{"if (null) { 1 } else { 2 }", 2},
+		{"if (null == null) { 1 }", 1},
+		{"if (null != 0) { 1 }", 1},
+		{"let a = [null]; a[0]", nil},
+		{"let f = fn() { null }; f()", nil},
+	}
+
+	for _, tc := range testCases {
+		evaluated := setupEval(tc.input)
+		integer, ok := tc.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+
+	runEvalErrorTests(t, []evalErrorTestCase{{"if (false) { 1 } else if (1 / 0) { 2 }", "division by zero"}})
+}
+
+func TestEvalUnicodeStrings(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{`"héllo"[1]`, "é"},
+		{`"a😀b"[1]`, "😀"},
+		{`"héllo"[5]`, nil},
+		{`"héllo"[-1]`, nil},
+		{`"héllo wörld"[1:4]`, "éll"},
+		{`"héllo"[:2]`, "hé"},
+		{`"héllo"[3:]`, "lo"},
+		{`"héllo"[:]`, "héllo"},
+		{`"héllo"[-5:99]`, "héllo"},
+		{`"héllo"[4:2]`, ""},
+		{`let s = "😀😀"; len(s[1:])`, 1},
+		{`let 名前 = "ok"; 名前`, "ok"},
+		{`let a = [1, 2, 3, 4]; a[1:3]`, []int{2, 3}},
+		{`let a = [1, 2, 3]; let b = a[:]; b[0] = 9; a[0]`, 1},
+		{`[1, 2][2:]`, []int{}},
+		{`[1, 2][:"x"]`, "slice bound must be INTEGER, got STRING"},
+		{`1[1:]`, "slice operator not supported: INTEGER"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := setupEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok || len(array.Items) != len(expected) {
+				t.Errorf("%s: evaluated = %+v, want %d items", tc.input, evaluated, len(expected))
+				continue
+			}
+			for i, item := range expected {
+				testIntegerObject(t, array.Items[i], int64(item))
+			}
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("%s: errObj.Message = %q, want = %q", tc.input, errObj.Message, expected)
+				}
+				continue
+			}
+			strObj, ok := evaluated.(*object.String)
+			if !ok || strObj.Value != expected {
+				t.Errorf("%s: evaluated = %+v, want = %q", tc.input, evaluated, expected)
+			}
+		}
+	}
+}
+
+func TestEvalInterpolatedStrings(t *testing.T) {
+	testCases := []evalErrorTestCase{
+		{`let name = "Ann"; let age = 30; "Hello ${name}, you are ${age}"`, "Hello Ann, you are 30"},
+		{`"${1 + 2}${2.5}${true}"`, "32.5true"},
+		{`"a ${[1, "b"]} ${{"k": 1}["k"]}"`, "a [1, b] 1"},
+		{`let f = fn(x) { "<${x}>" }; "${f("y")}!"`, "<y>!"},
+		{`let x = if (false) { 1 }; "${x}"`, "null"},
+		{`"${"nested ${1}"} \${literal}"`, "nested 1 ${literal}"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := setupEval(tc.input)
+		strObj, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("%s: evaluated is not String, got = %T (%+v)", tc.input, evaluated, evaluated)
+			continue
+		}
+		if strObj.Value != tc.expected {
+			t.Errorf("strObj.Value = %q, want = %q", strObj.Value, tc.expected)
+		}
+	}
+
+	runEvalErrorTests(t, []evalErrorTestCase{{`"a ${1 / 0} b"`, "division by zero"}})
+}
+
+func TestEvalIndexAssignment(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"let a = [1, 2, 3]; a[0] = 10; a[0] + a[1]", 12},
+		{"let a = [1, 2, 3]; a[2] = 7", 7},
+		{`let m = {"x": 1}; m["y"] = 2; m["x"] + m["y"]`, 3},
+		{`let m = {}; m[true] = 5; m[true]`, 5},
+		{"let a = [1, 2]; a[1] += 5; a[1]", 7},
+		{`let m = {"n": 2}; m["n"] *= 3; m["n"]`, 6},
+		{"let a = [1]; let b = a; b[0] = 9; a[0]", 9},
+		{"let a = [[0]]; a[0][0] = 4; a[0][0]", 4},
+		{"let i = 0; let next = fn() { i += 1; i }; let a = [0, 0]; a[next()] += 3; [i, a[1]]", []int{1, 3}},
+		{"let a = [1]; let set = fn(arr) { arr[0] = 2 }; set(a); a[0]", 2},
+		{"let a = [1]; let b = push(a, 2); b[0] = 5; a[0]", 1},
+	}
+
+	for _, tc := range testCases {
+		evaluated := setupEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("%s: object is not Array. got = %T (%+v)", tc.input, evaluated, evaluated)
+				continue
+			}
+			for i, item := range expected {
+				testIntegerObject(t, array.Items[i], int64(item))
+			}
+		}
+	}
+
+	errorCases := []evalErrorTestCase{
+		{"let a = [1, 2]; a[2] = 0", "index out of range: 2 (array length 2)"},
+		{"let a = [1, 2]; a[-1] = 0", "index out of range: -1 (array length 2)"},
+		{`let a = [1]; a["x"] = 0`, "array index must be INTEGER, got STRING"},
+		{`let m = {}; m[[1]] = 0`, "unusable as hash key: ARRAY"},
+		{`let s = "abc"; s[0] = "x"`, "index assignment not supported: STRING"},
+	}
+	runEvalErrorTests(t, errorCases)
+}
+
+func TestEvalAssignExpressions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x = x + 1", 2},
+		{"let a = 1; let b = 2; a = b = 5; a + b", 10},
+		{"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x %= 4", 2},
+		{"let n = 0; for (let i = 0; i < 5; i += 1) { n += i; } n", 10},
+		{"let x = 1; let set = fn() { x = 5; }; set(); x", 5},
+		{"let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c(); c()", 3},
+		{"let counter = fn() { let n = 0; fn() { n += 1 } }; let a = counter(); let b = counter(); a(); a(); b()", 1},
+		{"let f = fn() { let x = 1; let g = fn() { x }; x = 2; g() }; f()", 2},
+		{"let f = fn() { let x = 1; let g = fn() { x }; let x = 2; g() }; f()", 2},
+		{"let f = fn(x) { let g = fn() { fn() { x *= 3 } }; g()(); x }; f(2)", 6},
+	}
+
+	for _, tc := range testCases {
+		testIntegerObject(t, setupEval(tc.input), tc.expected)
+	}
+
+	errorCases := []evalErrorTestCase{
+		{"y = 1", "identifier not found: y"},
+		{"y += 1", "identifier not found: y"},
+		{`let s = "a"; s -= 1`, "type mismatch: STRING - INTEGER"},
+	}
+	runEvalErrorTests(t, errorCases)
+}
+
+func TestEvalLoops(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int64
+	}{
+		{"let i = 0; while (i < 5) { let i = i + 1; } i", 5},
+		{"let i = 0; while (false) { let i = i + 1; } i", 0},
+		{"let n = 0; for (let i = 0; i < 4; let i = i + 1) { let n = n + i; } n", 6},
+		{"let i = 0; for (;;) { let i = i + 1; if (i == 3) { break; } } i", 3},
+		{"let n = 0; for (let i = 0; i < 10; let i = i + 1) { if (i % 2 == 0) { continue; } let n = n + i; } n", 25},
+		{"let i = 0; while (true) { let i = i + 1; if (i < 100000) { continue } break; } i", 100000},
+		{"let f = fn() { let i = 0; while (true) { let i = i + 1; if (i == 7) { return i * 2; } } }; f()", 14},
+		{"let a = 1; let h = fn() { a }; let a = 5; h()", 5},
+		{`let n = 0;
+for (let i = 0; i < 3; let i = i + 1) {
+  for (let j = 0; j < 3; let j = j + 1) {
+    if (j == i) { break; }
+    let n = n + 1;
+  }
+}
+n`, 3},
+	}
+
+	for _, tc := range testCases {
+		testIntegerObject(t, setupEval(tc.input), tc.expected)
+	}
+
+	runEvalErrorTests(t, []evalErrorTestCase{{"while (true) { 1 + true; }", "type mismatch: INTEGER + BOOLEAN"}})
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"1.5", 1.5},
+		{"2.5e2", 250.0},
+		{"1E-2", 0.01},
+		{"-0.5", -0.5},
+		{"0.1 + 0.2", 0.30000000000000004},
+		{"1.5 * 2", 3.0},
+		{"2 * 1.5", 3.0},
+		{"7 / 2.0", 3.5},
+		{"7 / 2", 3},
+		{"10 - 0.5", 9.5},
+		{"1 / 0.0", math.Inf(1)},
+		{"7.5 % 2", 1.5},
+		{"-7.5 % 2", -1.5},
+		{"1 == 1.0", true},
+		{"1.0 != 1", false},
+		{"2 > 1.5", true},
+		{"1.5 < 2", true},
+		{"1.5 > 2", false},
+		{"{1: 5}[1.0]", 5},
+	}
+
+	for _, tc := range testCases {
+		evaluated := setupEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
+	}
+}
+
 func TestMapIndexExpressions(t *testing.T) {
 	testCases := []struct {
 		input    string
@@ -177,6 +698,7 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("one")`, 3},
 		{`len("hello world!")`, 12},
+		{`len("héllo 😀")`, 7},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 		{`len([1, 2, 3])`, 3},
@@ -320,10 +842,7 @@ func TestEvalLetStatements(t *testing.T) {
 }
 
 func TestErrorHandling(t *testing.T) {
-	testCases := []struct {
-		input    string
-		expected string
-	}{
+	testCases := []evalErrorTestCase{
 		{
 			"5 + true;",
 			"type mismatch: INTEGER + BOOLEAN",
@@ -371,20 +890,16 @@ return 1;
 			`{"key": "value"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"1 / 0",
+			"division by zero",
+		},
+		{
+			"let f = fn(a, b) { a % b }; f(10, 5 - 5)",
+			"modulo by zero",
+		},
 	}
-
-	for _, tc := range testCases {
-		evaluated := setupEval(tc.input)
-
-		errObj, ok := evaluated.(*object.Error)
-		if !ok {
-			t.Errorf("no error object returned. got = %T (%+v)", evaluated, evaluated)
-			continue
-		}
-		if errObj.Message != tc.expected {
-			t.Errorf("errObj.Message = %q, want = %q", errObj.Message, tc.expected)
-		}
-	}
+	runEvalErrorTests(t, testCases)
 }
 
 func TestEvalReturnStatements(t *testing.T) {
@@ -427,6 +942,8 @@ func TestEvalIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (true) { let x = 1; }", nil},
+		{"if (false) { 10 } else { while (false) {} }", nil},
 	}
 
 	for _, tc := range testCases {
@@ -465,6 +982,11 @@ func TestEvalBooleanExpression(t *testing.T) {
 	}{
 		{"true", true},
 		{"false", false},
+		{`"a" == "a"`, true},
+		{`"a" + "b" == "ab"`, true},
+		{`"a" != "a"`, false},
+		{`"a" != "b"`, true},
+		{`"1" == 1`, false},
 		{"1 < 2", true},
 		{"1 > 2", false},
 		{"1 < 1", false},
@@ -482,6 +1004,24 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1.5 <= 1", false},
+		{"1 >= 0.5", true},
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"1 && 0", true},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		{"false && (1 + true)", false},
+		{"true || (1 + true)", true},
+		{"true || false && false", true},
 	}
 
 	for _, tc := range testCases {
@@ -504,6 +1044,11 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"-50 + 100 + -50", 0},
 		{"20 + 2 * -10", 0},
 		{"50 / 2 * 2 + 10", 60},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"7 % -3", 1},
+		{"-7 / 2", -3},
+		{"1 + 10 % 4 * 2", 5},
 		{"5 * 2 + 10", 20},
 		{"5 + 2 * 10", 25},
 		{"3 * (3 * 3) + 10", 37},
@@ -518,6 +1063,28 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+// evalErrorTestCase is a program whose evaluation must end in an error of the expected message.
+type evalErrorTestCase struct {
+	input    string
+	expected string
+}
+
+func runEvalErrorTests(t *testing.T, testCases []evalErrorTestCase) {
+	t.Helper()
+
+	for _, tc := range testCases {
+		evaluated := setupEval(tc.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned. got = %T (%+v)", tc.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tc.expected {
+			t.Errorf("%s: errObj.Message = %q, want = %q", tc.input, errObj.Message, tc.expected)
+		}
+	}
+}
+
 func setupEval(input string) object.IObject {
 	l := lexer.NewLexer(input)
 	p := parser.NewParser(l)
@@ -540,6 +1107,20 @@ func testIntegerObject(t *testing.T, obj object.IObject, expected int64) bool {
 	return true
 }
 
+func testFloatObject(t *testing.T, obj object.IObject, expected float64) bool {
+	floatObj, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("obj is not Float, got = %T (%+v)", obj, obj)
+		return false
+	}
+
+	if floatObj.Value != expected {
+		t.Errorf("floatObj.Value = %g, want %g", floatObj.Value, expected)
+		return false
+	}
+	return true
+}
+
 func testBooleanObject(t *testing.T, obj object.IObject, expected bool) bool {
 	boolObj, ok := obj.(*object.Boolean)
 	if !ok {
@@ -2,41 +2,172 @@ package lexer
 
 import (
 	"BigTalk_Interpreter/token"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Lexer struct {
 	input        string
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input (after current char)
-	chr          byte // current char under examination
+	chr          rune // current char under examination
+
+	filename string
+	line     int // line of the current char
+	column   int // column of the current char, counted in characters rather than bytes
+
+	errors []Error
+
+	// interpolations holds, for each `${` of an interpolated string the lexer is inside of,
+	// the number of `{` opened by the embedded expression, innermost last. The `}` seen at
+	// depth 0 resumes the string.
+	interpolations []int
+}
+
+// Error is a problem found while scanning, such as an unterminated block comment.
+type Error struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
 }
 
 func NewLexer(input string) *Lexer {
-	lexer := &Lexer{input: input}
+	return NewLexerWithFilename("", input)
+}
+
+// NewLexerWithFilename creates a lexer whose token positions refer to the given file.
+func NewLexerWithFilename(filename string, input string) *Lexer {
+	lexer := &Lexer{input: input, filename: filename, line: 1}
 	lexer.readChar()
 	return lexer
 }
 
 // readChar reads the next character from the input string and updates the lexer's state.
 // If the read position is at the end of the input string, the current character is set to 0 to indicate the end of the input.
-// Otherwise, the current character is set to the character at the read position in the input string.
-// The position, readPosition, and readPosition fields are updated accordingly.
+// Otherwise, the current character is set to the UTF-8 encoded character at the read position in the input string.
+// The position, readPosition, line and column fields are updated accordingly.
+// A byte that is not valid UTF-8 is reported and read as utf8.RuneError.
 func (l *Lexer) readChar() {
+	if l.chr == '\n' {
+		l.line++
+		l.column = 0
+	}
+
+	width := 1
 	if l.readPosition >= len(l.input) {
 		l.chr = 0
 	} else {
-		l.chr = l.input[l.readPosition]
+		l.chr, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += width
+	// Reading past the end keeps the EOF position stable.
+	if l.position <= len(l.input) {
+		l.column++
+	}
+
+	if l.chr == utf8.RuneError && width == 1 {
+		l.addError(l.currentPosition(), "invalid UTF-8 encoding")
+	}
+}
+
+// Errors returns the problems found in the input scanned so far.
+func (l *Lexer) Errors() []Error {
+	return l.errors
+}
+
+func (l *Lexer) addError(pos token.Position, message string) {
+	l.errors = append(l.errors, Error{Pos: pos, Message: message})
+}
+
+// currentPosition returns the source position of the character under examination.
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{Filename: l.filename, Offset: l.position, Line: l.line, Column: l.column}
 }
 
 // NextToken gets the next token from the input string and returns it. It uses a lexer to identify the type of token and its literal value.
-// The function starts by eating any leading whitespace characters by calling `l.eatWhitespace()`.
-// Then, it uses a switch statement to check the current character `l.chr` and assign the appropriate token type and literal value to the `tok` variable. The possible cases are: '=',
+// The function starts by skipping any leading whitespace and comments by calling `l.skipTrivia()`.
+// The token is then scanned by readToken and stamped with the positions of its first character and of the character after it.
+// The skipped comments are attached to the token.
 func (l *Lexer) NextToken() token.Token {
+	comments := l.skipTrivia()
+
+	start := l.currentPosition()
+	tok := l.readToken()
+	tok.Pos = start
+	tok.End = l.currentPosition()
+	tok.Comments = comments
+	return tok
+}
+
+// skipTrivia skips whitespace and comments and returns the comments in source order.
+func (l *Lexer) skipTrivia() []token.Comment {
+	var comments []token.Comment
+	for {
+		l.eatWhitespace()
+		if l.chr != '/' {
+			return comments
+		}
+
+		switch l.peekChar() {
+		case '/':
+			comments = append(comments, l.readLineComment())
+		case '*':
+			comments = append(comments, l.readBlockComment())
+		default:
+			return comments
+		}
+	}
+}
+
+// readLineComment reads a `//` comment up to, but not including, the end of the line.
+func (l *Lexer) readLineComment() token.Comment {
+	pos := l.currentPosition()
+	for l.chr != '\n' && l.chr != 0 {
+		l.readChar()
+	}
+	return token.Comment{Text: l.input[pos.Offset:l.position], Pos: pos}
+}
+
+// readBlockComment reads a `/* */` comment. Block comments do not nest: a nested `/*` is
+// reported as an error but still matched with its `*/`, so the rest of the comment is not
+// scanned as code. A comment left open at the end of the input is reported as well.
+func (l *Lexer) readBlockComment() token.Comment {
+	pos := l.currentPosition()
+	l.readChar()
+	l.readChar()
+
+	depth := 1
+	for depth > 0 {
+		switch {
+		case l.chr == 0:
+			l.addError(pos, "unterminated block comment")
+			depth = 0
+		case l.chr == '/' && l.peekChar() == '*':
+			l.addError(l.currentPosition(), "nested block comments are not supported")
+			depth++
+			l.readChar()
+			l.readChar()
+		case l.chr == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+			l.readChar()
+		default:
+			l.readChar()
+		}
+	}
+	return token.Comment{Text: l.input[pos.Offset:l.position], Pos: pos}
+}
+
+// readToken uses a switch statement to check the current character `l.chr` and assign the appropriate token type and literal value to the `tok` variable.
+func (l *Lexer) readToken() token.Token {
 	var tok token.Token
-	l.eatWhitespace()
 
 	switch l.chr {
 	case '=':
@@ -44,6 +175,9 @@ func (l *Lexer) NextToken() token.Token {
 			chr := l.chr
 			l.readChar()
 			tok = token.Token{Type: token.EQ, Literal: string(chr) + string(l.chr)}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: "=>"}
 		} else {
 			tok = token.Token{Type: token.ASSIGN, Literal: string(l.chr)}
 		}
@@ -56,13 +190,23 @@ func (l *Lexer) NextToken() token.Token {
 	case ',':
 		tok = token.Token{Type: token.COMMA, Literal: string(l.chr)}
 	case '+':
-		tok = token.Token{Type: token.PLUS, Literal: string(l.chr)}
+		tok = l.readOperator(token.PLUS, token.PLUS_ASSIGN)
 	case '{':
+		if n := len(l.interpolations); n > 0 {
+			l.interpolations[n-1]++
+		}
 		tok = token.Token{Type: token.LBRACE, Literal: string(l.chr)}
 	case '}':
-		tok = token.Token{Type: token.RBRACE, Literal: string(l.chr)}
+		if n := len(l.interpolations); n > 0 && l.interpolations[n-1] == 0 {
+			tok = l.readStringContinuation()
+		} else {
+			if n > 0 {
+				l.interpolations[n-1]--
+			}
+			tok = token.Token{Type: token.RBRACE, Literal: string(l.chr)}
+		}
 	case '-':
-		tok = token.Token{Type: token.MINUS, Literal: string(l.chr)}
+		tok = l.readOperator(token.MINUS, token.MINUS_ASSIGN)
 	case '!':
 		if l.peekChar() == '=' {
 			chr := l.chr
@@ -72,33 +216,71 @@ func (l *Lexer) NextToken() token.Token {
 			tok = token.Token{Type: token.BANG, Literal: string(l.chr)}
 		}
 	case '/':
-		tok = token.Token{Type: token.SLASH, Literal: string(l.chr)}
+		tok = l.readOperator(token.SLASH, token.SLASH_ASSIGN)
 	case '*':
-		tok = token.Token{Type: token.ASTERISK, Literal: string(l.chr)}
+		tok = l.readOperator(token.ASTERISK, token.ASTERISK_ASSIGN)
+	case '%':
+		tok = l.readOperator(token.PERCENT, token.PERCENT_ASSIGN)
 	case '<':
-		tok = token.Token{Type: token.LT, Literal: string(l.chr)}
+		if l.peekChar() == '=' {
+			chr := l.chr
+			l.readChar()
+			tok = token.Token{Type: token.LT_EQ, Literal: string(chr) + string(l.chr)}
+		} else {
+			tok = token.Token{Type: token.LT, Literal: string(l.chr)}
+		}
 	case '>':
-		tok = token.Token{Type: token.GT, Literal: string(l.chr)}
+		if l.peekChar() == '=' {
+			chr := l.chr
+			l.readChar()
+			tok = token.Token{Type: token.GT_EQ, Literal: string(chr) + string(l.chr)}
+		} else {
+			tok = token.Token{Type: token.GT, Literal: string(l.chr)}
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			chr := l.chr
+			l.readChar()
+			tok = token.Token{Type: token.AND, Literal: string(chr) + string(l.chr)}
+		} else {
+			tok = token.Token{Type: token.ILLEGAL, Literal: string(l.chr)}
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			chr := l.chr
+			l.readChar()
+			tok = token.Token{Type: token.OR, Literal: string(chr) + string(l.chr)}
+		} else {
+			tok = token.Token{Type: token.ILLEGAL, Literal: string(l.chr)}
+		}
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok = l.readString()
+	case '`':
+		tok = l.readRawString()
 	case '[':
 		tok = token.Token{Type: token.L_SQR_BRACKET, Literal: string(l.chr)}
 	case ']':
 		tok = token.Token{Type: token.R_SQR_BRACKET, Literal: string(l.chr)}
 	case ':':
 		tok = token.Token{Type: token.COLON, Literal: string(l.chr)}
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = token.Token{Type: token.ILLEGAL, Literal: string(l.chr)}
+		}
 	default:
 		if isLetter(l.chr) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdentifier(tok.Literal)
 			return tok
 		} else if isDigit(l.chr) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Type, tok.Literal = l.readNumber()
 			return tok
 		} else {
 			tok = token.Token{Type: token.ILLEGAL, Literal: string(l.chr)}
@@ -126,44 +308,238 @@ func (l *Lexer) eatWhitespace() {
 	}
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer or a float literal. A float has a fraction, an exponent or
+// both, e.g. `1.5`, `2e10` or `6.02E+23`. A `.` or `e` that is not followed by digits is
+// not part of the number.
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	position := l.position
+	var tokenType token.TokenType = token.INT
+
+	l.readDigits()
+	if l.chr == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+	if (l.chr == 'e' || l.chr == 'E') && l.exponentFollows() {
+		tokenType = token.FLOAT
+		l.readChar()
+		if l.chr == '+' || l.chr == '-' {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+	return tokenType, l.input[position:l.position]
+}
+
+func (l *Lexer) readDigits() {
 	for isDigit(l.chr) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+}
+
+// exponentFollows reports whether the `e` under examination starts an exponent, i.e. it is
+// followed by digits with an optional sign.
+func (l *Lexer) exponentFollows() bool {
+	next := l.readPosition
+	if next < len(l.input) && (l.input[next] == '+' || l.input[next] == '-') {
+		next++
+	}
+	return next < len(l.input) && isDigit(rune(l.input[next]))
+}
+
+// readOperator reads an arithmetic operator, or its compound assignment form when the
+// operator is directly followed by `=`.
+func (l *Lexer) readOperator(single, compound token.TokenType) token.Token {
+	if l.peekChar() == '=' {
+		chr := l.chr
+		l.readChar()
+		return token.Token{Type: compound, Literal: string(chr) + string(l.chr)}
+	}
+	return token.Token{Type: single, Literal: string(l.chr)}
 }
 
 // peekChar returns the next character in the lexer's input string without advancing the read position.
 // If the read position is at the end of the input string, it returns 0 to indicate the end of the input.
 // Otherwise, it returns the character at the read position in the input string.
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	}
-	return l.input[l.readPosition]
+	chr, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return chr
+}
+
+// readString reads a double quoted string literal and returns it as a STRING token whose
+// literal is the decoded value. The escapes \n, \t, \r, \", \\, \$ and \u{...} are supported,
+// an unknown escape is reported and kept as the escaped character. The literal may span
+// lines. One left open until the end of the input is reported at its opening quote and
+// returned as an ILLEGAL token holding the rest of that line, scanning resumes on the next.
+// A `${` starting an embedded expression ends the token as a STRING_HEAD instead, see
+// readStringSegment. The lexer is left on the closing quote, on the `{` of the `${` or
+// on the character that ended the literal.
+func (l *Lexer) readString() token.Token {
+	return l.readStringSegment(token.STRING, token.STRING_HEAD)
+}
+
+// readStringContinuation resumes the interpolated string whose embedded expression is
+// closed by the current `}` and reads its next STRING_MID or STRING_TAIL segment.
+func (l *Lexer) readStringContinuation() token.Token {
+	l.interpolations = l.interpolations[:len(l.interpolations)-1]
+	return l.readStringSegment(token.STRING_TAIL, token.STRING_MID)
 }
 
-// readString reads a string from the input starting from the current position.
-// It advances the lexer until it encounters a closing double quote character or reaches the end of the input.
-// The substring between the current position and the position before the closing double quote is returned as the string.
-// If the lexer reaches the end of the input without encountering a closing double quote, the substring until the end of the input is returned.
-// The position of the lexer is updated accordingly.
-func (l *Lexer) readString() string {
-	pos := l.position + 1
+// readStringSegment reads the text after the current `"` or `}` up to the closing quote,
+// returned as a closed token, or up to a `${`, returned as an open token. An open token
+// pushes an interpolation so the matching `}` resumes the string. A segment left open is
+// reported at its first character.
+func (l *Lexer) readStringSegment(closed, open token.TokenType) token.Token {
+	start := l.currentPosition()
+	var value strings.Builder
+
+	l.readChar()
 	for {
+		switch l.chr {
+		case '"':
+			return token.Token{Type: closed, Literal: value.String()}
+		case '$':
+			if l.peekChar() == '{' {
+				l.readChar()
+				l.interpolations = append(l.interpolations, 0)
+				return token.Token{Type: open, Literal: value.String()}
+			}
+			value.WriteRune(l.chr)
+			l.readChar()
+		case 0:
+			l.resumeAfterLine(start)
+			l.addError(start, "unterminated string literal")
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[start.Offset:l.position]}
+		case '\\':
+			l.readEscape(&value)
+		default:
+			value.WriteRune(l.chr)
+			l.readChar()
+		}
+	}
+}
+
+// readEscape decodes the escape sequence starting at the current backslash into value and
+// leaves the lexer on the character after it.
+func (l *Lexer) readEscape(value *strings.Builder) {
+	pos := l.currentPosition()
+	l.readChar()
+
+	switch l.chr {
+	case 'n':
+		value.WriteByte('\n')
+	case 't':
+		value.WriteByte('\t')
+	case 'r':
+		value.WriteByte('\r')
+	case '"', '\\', '$':
+		value.WriteRune(l.chr)
+	case 'u':
+		l.readUnicodeEscape(pos, value)
+		return
+	case 0:
+		// The unterminated literal is reported by readStringSegment.
+		return
+	case '\n':
+		l.addError(pos, "unknown escape sequence \\ at the end of a line")
+		value.WriteByte('\n')
+	default:
+		l.addError(pos, fmt.Sprintf("unknown escape sequence \\%c", l.chr))
+		value.WriteRune(l.chr)
+	}
+	l.readChar()
+}
+
+// readUnicodeEscape decodes `\u{XXXX}`, one to six hex digits naming a Unicode code point,
+// with the lexer on the `u`. pos is the position of the backslash.
+func (l *Lexer) readUnicodeEscape(pos token.Position, value *strings.Builder) {
+	l.readChar()
+	if l.chr != '{' {
+		l.addError(pos, "invalid unicode escape, expected \\u{...}")
+		return
+	}
+	l.readChar()
+
+	digits := l.position
+	for isHexDigit(l.chr) {
 		l.readChar()
-		if l.chr == '"' || l.chr == 0 {
-			break
+	}
+	hex := l.input[digits:l.position]
+
+	if l.chr != '}' {
+		l.addError(pos, "unterminated unicode escape")
+		return
+	}
+	l.readChar()
+
+	code, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) > 6 || !utf8.ValidRune(rune(code)) {
+		l.addError(pos, fmt.Sprintf("invalid unicode code point \\u{%s}", hex))
+		return
+	}
+	value.WriteRune(rune(code))
+}
+
+// readRawString reads a backtick quoted string. Its text is taken verbatim, without escapes,
+// and may span several lines. A literal left open at the end of the input is reported and
+// returned as an ILLEGAL token holding the rest of its first line, scanning resumes on the
+// next.
+func (l *Lexer) readRawString() token.Token {
+	start := l.currentPosition()
+
+	l.readChar()
+	for l.chr != '`' && l.chr != 0 {
+		l.readChar()
+	}
+
+	if l.chr == 0 {
+		l.resumeAfterLine(start)
+		l.addError(start, "unterminated raw string literal")
+		return token.Token{Type: token.ILLEGAL, Literal: l.input[start.Offset:l.position]}
+	}
+	return token.Token{Type: token.STRING, Literal: l.input[start.Offset+1 : l.position]}
+}
+
+// resumeAfterLine moves the lexer back from the end of the input to the end of the line
+// holding pos, the start of a literal that was never closed. The lines after it are scanned
+// again as code, so the errors found in them while reading the literal are dropped.
+func (l *Lexer) resumeAfterLine(pos token.Position) {
+	end := len(l.input)
+	if i := strings.IndexByte(l.input[pos.Offset:], '\n'); i >= 0 {
+		end = pos.Offset + i
+	}
+
+	errors := l.errors[:0]
+	for _, err := range l.errors {
+		if err.Pos.Offset < end {
+			errors = append(errors, err)
 		}
 	}
-	return l.input[pos:l.position]
+	l.errors = errors
+
+	if end == len(l.input) {
+		return
+	}
+	l.position = end
+	l.readPosition = end + 1
+	l.chr = '\n'
+	l.line = pos.Line
+	l.column = pos.Column + utf8.RuneCountInString(l.input[pos.Offset:end])
 }
 
-func isLetter(chr byte) bool {
-	return 'a' <= chr && chr <= 'z' || 'A' <= chr && chr <= 'Z' || chr == '_'
+// isLetter reports whether chr may appear in an identifier, i.e. it is a Unicode letter or `_`.
+func isLetter(chr rune) bool {
+	return unicode.IsLetter(chr) || chr == '_'
 }
 
-func isDigit(chr byte) bool {
+func isDigit(chr rune) bool {
 	return '0' <= chr && chr <= '9'
 }
+
+func isHexDigit(chr rune) bool {
+	return isDigit(chr) || 'a' <= chr && chr <= 'f' || 'A' <= chr && chr <= 'F'
+}
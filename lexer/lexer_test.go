@@ -2,9 +2,431 @@ package lexer
 
 import (
 	"BigTalk_Interpreter/token"
+	"reflect"
 	"testing"
 )
 
+func TestNextToken_TryCatch(t *testing.T) {
+	input := "try { throw e } catch (e) {} finally {} tryhard"
+
+	expected := []token.TokenType{
+		token.TRY, token.LBRACE, token.THROW, token.IDENT, token.RBRACE, token.CATCH, token.LPAREN, token.IDENT,
+		token.RPAREN, token.LBRACE, token.RBRACE, token.FINALLY, token.LBRACE, token.RBRACE, token.IDENT, token.EOF,
+	}
+
+	l := NewLexer(input)
+	for i, want := range expected {
+		tok := l.NextToken()
+		if tok.Type != want {
+			t.Fatalf("expected[%d] - tok.Type = %q, want = %q", i, tok.Type, want)
+		}
+	}
+}
+
+func TestNextToken_Match(t *testing.T) {
+	input := "match (x) { 1 => a, _ => b } = >=>"
+
+	expected := []token.TokenType{
+		token.MATCH, token.LPAREN, token.IDENT, token.RPAREN, token.LBRACE, token.INT, token.ARROW, token.IDENT,
+		token.COMMA, token.IDENT, token.ARROW, token.IDENT, token.RBRACE, token.ASSIGN, token.GT_EQ, token.GT,
+		token.EOF,
+	}
+
+	l := NewLexer(input)
+	for i, want := range expected {
+		tok := l.NextToken()
+		if tok.Type != want {
+			t.Fatalf("expected[%d] - tok.Type = %q, want = %q", i, tok.Type, want)
+		}
+	}
+}
+
+func TestNextToken_Ellipsis(t *testing.T) {
+	input := "fn(a, ...rest) 1.5... .."
+
+	expected := []token.TokenType{
+		token.FUNCTION, token.LPAREN, token.IDENT, token.COMMA, token.ELLIPSIS, token.IDENT, token.RPAREN,
+		token.FLOAT, token.ELLIPSIS, token.ILLEGAL, token.ILLEGAL, token.EOF,
+	}
+
+	l := NewLexer(input)
+	for i, want := range expected {
+		tok := l.NextToken()
+		if tok.Type != want {
+			t.Fatalf("expected[%d] - tok.Type = %q, want = %q", i, tok.Type, want)
+		}
+	}
+}
+
+func TestNextToken_Unicode(t *testing.T) {
+	input := "let héllo = \"wörld 😀\";\nπ × 名前"
+
+	testCases := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     string
+	}{
+		{token.LET, "let", "1:1"},
+		{token.IDENT, "héllo", "1:5"},
+		{token.ASSIGN, "=", "1:11"},
+		{token.STRING, "wörld 😀", "1:13"},
+		{token.SEMICOLON, ";", "1:22"},
+		{token.IDENT, "π", "2:1"},
+		{token.ILLEGAL, "×", "2:3"},
+		{token.IDENT, "名前", "2:5"},
+		{token.EOF, "", "2:7"},
+	}
+
+	l := NewLexer(input)
+	for i, tc := range testCases {
+		tok := l.NextToken()
+		if tok.Type != tc.expectedType || tok.Literal != tc.expectedLiteral {
+			t.Fatalf("testCases[%d] - tok = %s %q, want = %s %q", i, tok.Type, tok.Literal, tc.expectedType, tc.expectedLiteral)
+		}
+		if tok.Pos.String() != tc.expectedPos {
+			t.Errorf("testCases[%d] - tok.Pos = %s, want = %s", i, tok.Pos, tc.expectedPos)
+		}
+	}
+	if len(l.Errors()) != 0 {
+		t.Errorf("l.Errors() = %v, want none", l.Errors())
+	}
+
+	l = NewLexer("\"a\xffb\"")
+	if tok := l.NextToken(); tok.Type != token.STRING || tok.Literal != "a\uFFFDb" {
+		t.Errorf("tok = %s %q, want = STRING \"a\\uFFFDb\"", tok.Type, tok.Literal)
+	}
+	if len(l.Errors()) != 1 || l.Errors()[0].Error() != "1:3: invalid UTF-8 encoding" {
+		t.Errorf("l.Errors() = %v, want the invalid byte reported", l.Errors())
+	}
+}
+
+func TestNextToken_StringInterpolation(t *testing.T) {
+	input := `"Hello ${name}, you are ${age + 1}" "${ {"a": 1}["a"] }" "${"in ${x}"}" "\${no} $x"`
+
+	testCases := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRING_HEAD, "Hello "},
+		{token.IDENT, "name"},
+		{token.STRING_MID, ", you are "},
+		{token.IDENT, "age"},
+		{token.PLUS, "+"},
+		{token.INT, "1"},
+		{token.STRING_TAIL, ""},
+		{token.STRING_HEAD, ""},
+		{token.LBRACE, "{"},
+		{token.STRING, "a"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.L_SQR_BRACKET, "["},
+		{token.STRING, "a"},
+		{token.R_SQR_BRACKET, "]"},
+		{token.STRING_TAIL, ""},
+		{token.STRING_HEAD, ""},
+		{token.STRING_HEAD, "in "},
+		{token.IDENT, "x"},
+		{token.STRING_TAIL, ""},
+		{token.STRING_TAIL, ""},
+		{token.STRING, "${no} $x"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+	for i, tc := range testCases {
+		tok := l.NextToken()
+		if tok.Type != tc.expectedType || tok.Literal != tc.expectedLiteral {
+			t.Fatalf("testCases[%d] - tok = %s %q, want = %s %q", i, tok.Type, tok.Literal, tc.expectedType, tc.expectedLiteral)
+		}
+	}
+	if len(l.Errors()) != 0 {
+		t.Errorf("l.Errors() = %v, want none", l.Errors())
+	}
+}
+
+func TestNextToken_Strings(t *testing.T) {
+	input := "\"a\\tb\\nc\" \"say \\\"hi\\\"\" \"back\\\\slash\\r\" \"\\u{48}\\u{e9}\\u{1F600}\" `raw \\n\nline` \"\" \"two\nlines\""
+
+	testCases := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRING, "a\tb\nc"},
+		{token.STRING, `say "hi"`},
+		{token.STRING, "back\\slash\r"},
+		{token.STRING, "H\u00e9\U0001F600"},
+		{token.STRING, "raw \\n\nline"},
+		{token.STRING, ""},
+		{token.STRING, "two\nlines"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+	for i, tc := range testCases {
+		tok := l.NextToken()
+		if tok.Type != tc.expectedType || tok.Literal != tc.expectedLiteral {
+			t.Fatalf("testCases[%d] - tok = %s %q, want = %s %q", i, tok.Type, tok.Literal, tc.expectedType, tc.expectedLiteral)
+		}
+	}
+	if len(l.Errors()) != 0 {
+		t.Errorf("l.Errors() = %v, want none", l.Errors())
+	}
+}
+
+func TestNextToken_StringErrors(t *testing.T) {
+	testCases := []struct {
+		input          string
+		expectedTokens []token.Token
+		expectedErrors []string
+	}{
+		{
+			"let s = \"open;\nlet t = 1;",
+			[]token.Token{
+				{Type: token.LET, Literal: "let"}, {Type: token.IDENT, Literal: "s"}, {Type: token.ASSIGN, Literal: "="},
+				{Type: token.ILLEGAL, Literal: `"open;`}, {Type: token.LET, Literal: "let"}, {Type: token.IDENT, Literal: "t"},
+				{Type: token.ASSIGN, Literal: "="}, {Type: token.INT, Literal: "1"}, {Type: token.SEMICOLON, Literal: ";"},
+			},
+			[]string{"1:9: unterminated string literal"},
+		},
+		{
+			"\"open \\q\ny \xff",
+			[]token.Token{{Type: token.ILLEGAL, Literal: `"open \q`}, {Type: token.IDENT, Literal: "y"}, {Type: token.ILLEGAL, Literal: "\uFFFD"}},
+			[]string{`1:7: unknown escape sequence \q`, "1:1: unterminated string literal", "2:3: invalid UTF-8 encoding"},
+		},
+		{
+			"x `never closed\n}",
+			[]token.Token{{Type: token.IDENT, Literal: "x"}, {Type: token.ILLEGAL, Literal: "`never closed"}, {Type: token.RBRACE, Literal: "}"}},
+			[]string{"1:3: unterminated raw string literal"},
+		},
+		{
+			`"\q \u{110000} \u{zz} \u41"`,
+			[]token.Token{{Type: token.STRING, Literal: "q  zz} 41"}},
+			[]string{
+				`1:2: unknown escape sequence \q`,
+				`1:5: invalid unicode code point \u{110000}`,
+				`1:16: unterminated unicode escape`,
+				`1:23: invalid unicode escape, expected \u{...}`,
+			},
+		},
+		{
+			"\"a\\\nb\"",
+			[]token.Token{{Type: token.STRING, Literal: "a\nb"}},
+			[]string{`1:3: unknown escape sequence \ at the end of a line`},
+		},
+	}
+
+	for _, tc := range testCases {
+		l := NewLexer(tc.input)
+		for i, want := range tc.expectedTokens {
+			tok := l.NextToken()
+			if tok.Type != want.Type || tok.Literal != want.Literal {
+				t.Fatalf("%q: token[%d] = %s %q, want = %s %q", tc.input, i, tok.Type, tok.Literal, want.Type, want.Literal)
+			}
+		}
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Fatalf("%q: last token = %s %q, want = EOF", tc.input, tok.Type, tok.Literal)
+		}
+
+		var errors []string
+		for _, err := range l.Errors() {
+			errors = append(errors, err.Error())
+		}
+		if !reflect.DeepEqual(errors, tc.expectedErrors) {
+			t.Errorf("%q: l.Errors() = %q, want = %q", tc.input, errors, tc.expectedErrors)
+		}
+	}
+}
+
+func TestNextToken_AssignmentOperators(t *testing.T) {
+	input := "x += 1 -= 2 *= 3 /= 4 %= 5 = 6"
+
+	expected := []token.TokenType{
+		token.IDENT, token.PLUS_ASSIGN, token.INT, token.MINUS_ASSIGN, token.INT, token.ASTERISK_ASSIGN,
+		token.INT, token.SLASH_ASSIGN, token.INT, token.PERCENT_ASSIGN, token.INT, token.ASSIGN, token.INT,
+		token.EOF,
+	}
+
+	l := NewLexer(input)
+	for i, want := range expected {
+		tok := l.NextToken()
+		if tok.Type != want {
+			t.Fatalf("expected[%d] - tok.Type = %q, want = %q", i, tok.Type, want)
+		}
+	}
+}
+
+func TestNextToken_LogicalOperators(t *testing.T) {
+	input := "a <= b >= c && d || e < f & g | h"
+
+	expected := []token.TokenType{
+		token.IDENT, token.LT_EQ, token.IDENT, token.GT_EQ, token.IDENT, token.AND, token.IDENT,
+		token.OR, token.IDENT, token.LT, token.IDENT, token.ILLEGAL, token.IDENT, token.ILLEGAL,
+		token.IDENT, token.EOF,
+	}
+
+	l := NewLexer(input)
+	for i, want := range expected {
+		tok := l.NextToken()
+		if tok.Type != want {
+			t.Fatalf("expected[%d] - tok.Type = %q, want = %q", i, tok.Type, want)
+		}
+	}
+}
+
+func TestNextToken_Numbers(t *testing.T) {
+	input := "0 42 3.14 1e9 2.5E-3 6e+2 7. 8.x 9e 1.5.2"
+
+	testCases := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "0"},
+		{token.INT, "42"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e9"},
+		{token.FLOAT, "2.5E-3"},
+		{token.FLOAT, "6e+2"},
+		{token.INT, "7"},
+		{token.ILLEGAL, "."},
+		{token.INT, "8"},
+		{token.ILLEGAL, "."},
+		{token.IDENT, "x"},
+		{token.INT, "9"},
+		{token.IDENT, "e"},
+		{token.FLOAT, "1.5"},
+		{token.ILLEGAL, "."},
+		{token.INT, "2"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+	for i, tc := range testCases {
+		tok := l.NextToken()
+		if tok.Type != tc.expectedType || tok.Literal != tc.expectedLiteral {
+			t.Fatalf("testCases[%d] - tok = %s %q, want = %s %q", i, tok.Type, tok.Literal, tc.expectedType, tc.expectedLiteral)
+		}
+	}
+}
+
+func TestNextToken_Comments(t *testing.T) {
+	input := `// leading
+let x = 10 / 2; /* block
+comment */ x // trailing`
+
+	testCases := []struct {
+		expectedType     token.TokenType
+		expectedComments []string
+	}{
+		{token.LET, []string{"// leading"}},
+		{token.IDENT, nil},
+		{token.ASSIGN, nil},
+		{token.INT, nil},
+		{token.SLASH, nil},
+		{token.INT, nil},
+		{token.SEMICOLON, nil},
+		{token.IDENT, []string{"/* block\ncomment */"}},
+		{token.EOF, []string{"// trailing"}},
+	}
+
+	l := NewLexer(input)
+	for i, tc := range testCases {
+		tok := l.NextToken()
+		if tok.Type != tc.expectedType {
+			t.Fatalf("testCases[%d] - tok.Type = %q, want = %q", i, tok.Type, tc.expectedType)
+		}
+
+		var comments []string
+		for _, comment := range tok.Comments {
+			comments = append(comments, comment.Text)
+		}
+		if !reflect.DeepEqual(comments, tc.expectedComments) {
+			t.Errorf("testCases[%d] - comments = %q, want = %q", i, comments, tc.expectedComments)
+		}
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Errorf("l.Errors() = %v, want none", l.Errors())
+	}
+}
+
+func TestNextToken_CommentErrors(t *testing.T) {
+	testCases := []struct {
+		input          string
+		expectedTypes  []token.TokenType
+		expectedErrors []string
+	}{
+		{
+			"1 /* never closed",
+			[]token.TokenType{token.INT, token.EOF},
+			[]string{"1:3: unterminated block comment"},
+		},
+		{
+			"/* outer /* inner */ still comment */ 2",
+			[]token.TokenType{token.INT, token.EOF},
+			[]string{"1:10: nested block comments are not supported"},
+		},
+	}
+
+	for _, tc := range testCases {
+		l := NewLexer(tc.input)
+
+		var types []token.TokenType
+		for tok := l.NextToken(); ; tok = l.NextToken() {
+			types = append(types, tok.Type)
+			if tok.Type == token.EOF {
+				break
+			}
+		}
+		if !reflect.DeepEqual(types, tc.expectedTypes) {
+			t.Errorf("input %q: token types = %v, want = %v", tc.input, types, tc.expectedTypes)
+		}
+
+		var errors []string
+		for _, err := range l.Errors() {
+			errors = append(errors, err.Error())
+		}
+		if !reflect.DeepEqual(errors, tc.expectedErrors) {
+			t.Errorf("input %q: errors = %q, want = %q", tc.input, errors, tc.expectedErrors)
+		}
+	}
+}
+
+func TestNextToken_Positions(t *testing.T) {
+	input := "let x = 5;\n  \"ab\" != x\n"
+
+	testCases := []struct {
+		expectedType token.TokenType
+		expectedPos  string
+		expectedEnd  string
+	}{
+		{token.LET, "main.bt:1:1", "main.bt:1:4"},
+		{token.IDENT, "main.bt:1:5", "main.bt:1:6"},
+		{token.ASSIGN, "main.bt:1:7", "main.bt:1:8"},
+		{token.INT, "main.bt:1:9", "main.bt:1:10"},
+		{token.SEMICOLON, "main.bt:1:10", "main.bt:1:11"},
+		{token.STRING, "main.bt:2:3", "main.bt:2:7"},
+		{token.NOT_EQ, "main.bt:2:8", "main.bt:2:10"},
+		{token.IDENT, "main.bt:2:11", "main.bt:2:12"},
+		{token.EOF, "main.bt:3:1", "main.bt:3:1"},
+		{token.EOF, "main.bt:3:1", "main.bt:3:1"},
+	}
+
+	l := NewLexerWithFilename("main.bt", input)
+	for i, tc := range testCases {
+		tok := l.NextToken()
+		if tok.Type != tc.expectedType {
+			t.Fatalf("testCases[%d] - tok.Type = %q, want = %q", i, tok.Type, tc.expectedType)
+		}
+		if tok.Pos.String() != tc.expectedPos {
+			t.Errorf("testCases[%d] - tok.Pos = %q, want = %q", i, tok.Pos, tc.expectedPos)
+		}
+		if tok.End.String() != tc.expectedEnd {
+			t.Errorf("testCases[%d] - tok.End = %q, want = %q", i, tok.End, tc.expectedEnd)
+		}
+	}
+}
+
 func TestNextToken(t *testing.T) {
 	input := `let five = 5;
 let ten = 10;
@@ -14,7 +436,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
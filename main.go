@@ -1,13 +1,62 @@
 package main
 
 import (
+	"BigTalk_Interpreter/ast"
+	"BigTalk_Interpreter/engine"
+	"BigTalk_Interpreter/lexer"
+	"BigTalk_Interpreter/parser"
 	"BigTalk_Interpreter/repl"
+	"flag"
 	"fmt"
 	"os"
 	"os/user"
+	"strings"
 )
 
+const usage = `Usage:
+  bigtalk [--engine=eval|vm]                   start the interactive REPL
+  bigtalk run [--engine=eval|vm] <file>        run a BigTalk script file
+  bigtalk compile <file> [-o <output.btc>]     compile a script to a bytecode file
+  bigtalk exec <file.btc>                      run a compiled bytecode file
+  bigtalk disasm <file.bt|file.btc>            print the disassembled bytecode of a program
+`
+
 func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	engineName := flag.String("engine", engine.DefaultEngine, engineFlagUsage())
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		os.Exit(startRepl(*engineName))
+	}
+
+	switch args[0] {
+	case "run":
+		os.Exit(runCommand(args[1:], *engineName))
+	case "compile":
+		os.Exit(compileCommand(args[1:]))
+	case "exec":
+		os.Exit(execCommand(args[1:]))
+	case "disasm":
+		os.Exit(disasmCommand(args[1:]))
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+		os.Exit(2)
+	}
+}
+
+func startRepl(engineName string) int {
+	eng, err := engine.New(engineName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
 	osUser, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -15,5 +64,59 @@ func main() {
 
 	fmt.Printf("Hello %s, this is the BigTalk programming language.", osUser.Username)
 	fmt.Printf("Type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	repl.StartWithEngine(os.Stdin, os.Stdout, eng)
+	return 0
+}
+
+func engineFlagUsage() string {
+	return fmt.Sprintf("execution engine to use (%s)", strings.Join(engine.Names(), ", "))
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	return fs
+}
+
+// parseArgs parses the flags of a subcommand and returns its positional arguments.
+// Unlike flag.FlagSet.Parse it also accepts flags after positional arguments,
+// e.g. `bigtalk compile main.bt -o main.btc`.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseSourceFile reads and parses a script, reporting any error on stderr.
+func parseSourceFile(path string) (*ast.Program, bool) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return nil, false
+	}
+
+	l := lexer.NewLexerWithFilename(path, string(source))
+	p := parser.NewParser(l)
+
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		for _, d := range p.Diagnostics() {
+			fmt.Fprintf(os.Stderr, "parse %s: %s\n", d.Severity, d)
+		}
+		return nil, false
+	}
+	return program, true
 }
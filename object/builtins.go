@@ -1,6 +1,9 @@
 package object
 
-import "fmt"
+import (
+	"fmt"
+	"unicode/utf8"
+)
 
 var BuiltinFunctions = []struct {
 	Name    string
@@ -15,7 +18,7 @@ var BuiltinFunctions = []struct {
 
 			switch arg := args[0].(type) {
 			case *String:
-				return &Integer{Value: int64(len(arg.Value))}
+				return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 			case *Array:
 				return &Integer{Value: int64(len(arg.Items))}
 			default:
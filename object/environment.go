@@ -29,6 +29,19 @@ func (e *Environment) Get(name string) (IObject, bool) {
 	return obj, ok
 }
 
+// Assign replaces the value of an existing binding in the innermost environment that holds
+// name. It reports false when name is not bound at all.
+func (e *Environment) Assign(name string, val IObject) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return false
+}
+
 func (e *Environment) Set(name string, val IObject) IObject {
 	e.store[name] = val
 	return val
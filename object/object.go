@@ -6,6 +6,8 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"strconv"
 	"strings"
 )
 
@@ -13,9 +15,12 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ           = "INTEGER"
+	FLOAT_OBJ             = "FLOAT"
 	BOOLEAN_OBJ           = "BOOLEAN"
 	NULL_OBJ              = "NULL"
 	RETURN_VALUE_OBJ      = "RETURN_VALUE"
+	BREAK_OBJ             = "BREAK"
+	CONTINUE_OBJ          = "CONTINUE"
 	ERROR_OBJ             = "ERROR"
 	FUNCTION_ONJ          = "FUNCTION"
 	STRING_OBJ            = "STRING"
@@ -24,6 +29,7 @@ const (
 	MAP_OBJ               = "HASH"
 	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION_OBJ"
 	CLOSURE_OBJ           = "CLOSURE"
+	CELL_OBJ              = "CELL"
 )
 
 type IObject interface {
@@ -52,6 +58,46 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+type Float struct {
+	Value float64
+}
+
+// Inspect prints the shortest representation of the value that reads back the same, keeping
+// a fraction on integral values so that floats stay distinguishable from integers.
+func (f *Float) Inspect() string {
+	out := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(out, ".eIN") {
+		out += ".0"
+	}
+	return out
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
+// HashKey gives integral floats the key of the equal Integer, so that 1.0 and 1 address
+// the same map entry just like 1.0 == 1.
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && math.Abs(f.Value) < math.MaxInt64 {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(int64(f.Value))}
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
+// ToFloat returns the value of an Integer or a Float as a float64. It is used to promote
+// the operands of mixed integer and float arithmetic.
+func ToFloat(obj IObject) (float64, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value), true
+	case *Float:
+		return obj.Value, true
+	default:
+		return 0, false
+	}
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -97,8 +143,32 @@ func (r *ReturnValue) Inspect() string {
 	return r.Value.Inspect()
 }
 
+// Break and Continue signal a `break` or `continue` to the evaluator's enclosing loop, the
+// same way ReturnValue unwinds to the enclosing function.
+type Break struct{}
+
+func (b *Break) Type() ObjectType {
+	return BREAK_OBJ
+}
+
+func (b *Break) Inspect() string {
+	return "break"
+}
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType {
+	return CONTINUE_OBJ
+}
+
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+
 type Error struct {
 	Message string
+	Thrown  IObject  // error value raised by a throw statement, nil for a runtime error
+	Stack   []string // names of the functions the error unwound so far, innermost first
 }
 
 func (e *Error) Type() ObjectType {
@@ -109,8 +179,76 @@ func (e *Error) Inspect() string {
 	return fmt.Sprintf("ERROR: %s", e.Message)
 }
 
+// Kinds of the error values a catch block receives. Errors raised by the interpreter itself
+// are of kind RuntimeErrorKind, a thrown value that is not a map of kind ThrownErrorKind.
+const (
+	RuntimeErrorKind = "RuntimeError"
+	ThrownErrorKind  = "Error"
+)
+
+// NewErrorValue returns the error value {"kind": kind, "message": message, "stack": stack}
+// scripts catch. The stack names the functions the error unwound, innermost first.
+func NewErrorValue(kind, message string, stack []string) *Map {
+	value := &Map{Pairs: map[HashKey]MapPair{}}
+	value.set("kind", &String{Value: kind})
+	value.set("message", &String{Value: message})
+	value.set("stack", stackArray(stack))
+	return value
+}
+
+// ThrownErrorValue returns the error value of a thrown value. Anything but a map becomes the
+// message of an error of kind ThrownErrorKind. A map is the error value itself, a copy of it
+// gets the kind and the stack when it lacks them, so a catch block can rethrow what it
+// caught unchanged.
+func ThrownErrorValue(value IObject, stack []string) IObject {
+	m, ok := value.(*Map)
+	if !ok {
+		return NewErrorValue(ThrownErrorKind, value.Inspect(), stack)
+	}
+
+	_, hasKind := m.get("kind")
+	_, hasStack := m.get("stack")
+	if hasKind && hasStack {
+		return m
+	}
+
+	completed := &Map{Pairs: make(map[HashKey]MapPair, len(m.Pairs)+2)}
+	for key, pair := range m.Pairs {
+		completed.Pairs[key] = pair
+	}
+	if !hasKind {
+		completed.set("kind", &String{Value: ThrownErrorKind})
+	}
+	if !hasStack {
+		completed.set("stack", stackArray(stack))
+	}
+	return completed
+}
+
+func stackArray(stack []string) *Array {
+	frames := make([]IObject, len(stack))
+	for i, name := range stack {
+		frames[i] = &String{Value: name}
+	}
+	return &Array{Items: frames}
+}
+
+// ErrorMessage returns the message of an error value, a map without a "message" is
+// described as a whole.
+func ErrorMessage(value IObject) string {
+	if m, ok := value.(*Map); ok {
+		if message, ok := m.get("message"); ok {
+			return message.Inspect()
+		}
+	}
+	return value.Inspect()
+}
+
 type Function struct {
+	Name       string // name the function was bound to with let, empty when anonymous
 	Parameters []*ast.Identifier
+	Defaults   []ast.IExpression // default value of each parameter, nil for a required one
+	Rest       *ast.Identifier   // rest parameter, nil when absent
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -121,11 +259,7 @@ func (f *Function) Type() ObjectType {
 
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
-	var params []string
-
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
+	params := ast.ParameterList(f.Parameters, f.Defaults, f.Rest)
 
 	out.WriteString("fn")
 	out.WriteString("(")
@@ -137,6 +271,49 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+// CheckArity reports an error when argsCount arguments cannot be bound to a function with
+// the given number of parameters, of which optional have a default value, and an optional
+// rest parameter.
+func CheckArity(argsCount, parameters, optional int, variadic bool) error {
+	required := parameters - optional
+	switch {
+	case argsCount < required && variadic:
+		return fmt.Errorf("wrong number of arguments: got = %d, want at least %d", argsCount, required)
+	case variadic || argsCount >= required && argsCount <= parameters:
+		return nil
+	case optional == 0:
+		return fmt.Errorf("wrong number of arguments: got = %d, want = %d", argsCount, parameters)
+	default:
+		return fmt.Errorf("wrong number of arguments: got = %d, want %d to %d", argsCount, required, parameters)
+	}
+}
+
+// SliceBounds resolves the bounds of a slice of a sequence with length elements. A Null
+// bound stands for the start or the end of the sequence, integer bounds are clamped to
+// the sequence and a start past the end yields an empty slice.
+func SliceBounds(low, high IObject, length int) (int, int, error) {
+	start, err := sliceBound(low, 0, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := sliceBound(high, length, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	return min(start, end), end, nil
+}
+
+func sliceBound(bound IObject, fallback, length int) (int, error) {
+	switch bound := bound.(type) {
+	case *Null:
+		return fallback, nil
+	case *Integer:
+		return int(max(0, min(bound.Value, int64(length)))), nil
+	default:
+		return 0, fmt.Errorf("slice bound must be INTEGER, got %s", bound.Type())
+	}
+}
+
 type String struct {
 	Value string
 }
@@ -219,14 +396,30 @@ func (m *Map) Inspect() string {
 	return out.String()
 }
 
+// get returns the value stored under a string key.
+func (m *Map) get(key string) (IObject, bool) {
+	pair, ok := m.Pairs[(&String{Value: key}).HashKey()]
+	return pair.Value, ok
+}
+
+// set stores value under a string key.
+func (m *Map) set(key string, value IObject) {
+	k := &String{Value: key}
+	m.Pairs[k.HashKey()] = MapPair{Key: k, Value: value}
+}
+
 type IHashable interface {
 	HashKey() HashKey
 }
 
 type CompiledFunction struct {
-	Instructions    code.Instructions
-	LocalsCount     int // Total number of local bindings the function would create
-	ParametersCount int
+	Instructions            code.Instructions
+	LocalsCount             int            // Total number of local bindings the function would create
+	ParametersCount         int            // Named parameters, not counting the rest parameter
+	OptionalParametersCount int            // Trailing parameters that have a default value
+	Variadic                bool           // Whether a rest parameter collects the extra arguments
+	Name                    string         // Name the function was bound to with let, empty when anonymous
+	SourceMap               code.SourceMap // Source positions of Instructions
 }
 
 func (c *CompiledFunction) Type() ObjectType {
@@ -239,7 +432,7 @@ func (c *CompiledFunction) Inspect() string {
 
 type Closure struct {
 	Fn            *CompiledFunction
-	FreeVariables []IObject
+	FreeVariables []*Cell
 }
 
 func (c *Closure) Type() ObjectType {
@@ -249,3 +442,17 @@ func (c *Closure) Type() ObjectType {
 func (c *Closure) Inspect() string {
 	return fmt.Sprintf("Closure[%p]", c)
 }
+
+// Cell holds a variable captured by a closure. The closure and the function that defined
+// the variable share the cell, so an assignment made by either one is seen by both.
+type Cell struct {
+	Value IObject
+}
+
+func (c *Cell) Type() ObjectType {
+	return CELL_OBJ
+}
+
+func (c *Cell) Inspect() string {
+	return c.Value.Inspect()
+}
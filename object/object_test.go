@@ -1,6 +1,147 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
+
+func TestThrownErrorValue(t *testing.T) {
+	field := func(value IObject, key string) string {
+		v, ok := value.(*Map).get(key)
+		if !ok {
+			return "<missing>"
+		}
+		return v.Inspect()
+	}
+
+	thrown := ThrownErrorValue(&Integer{Value: 1}, []string{"f", "g"})
+	kind, message, stack := field(thrown, "kind"), field(thrown, "message"), field(thrown, "stack")
+	if kind != "Error" || message != "1" || stack != "[f, g]" {
+		t.Errorf("ThrownErrorValue(1) = (%s, %s, %s), want = (Error, 1, [f, g])", kind, message, stack)
+	}
+
+	custom := &Map{Pairs: map[HashKey]MapPair{}}
+	custom.set("message", &String{Value: "m"})
+	thrown = ThrownErrorValue(custom, []string{"f"})
+	if kind, stack := field(thrown, "kind"), field(thrown, "stack"); kind != "Error" || stack != "[f]" {
+		t.Errorf("ThrownErrorValue(custom) = (%s, %s), want = (Error, [f])", kind, stack)
+	}
+	if len(custom.Pairs) != 1 {
+		t.Errorf("ThrownErrorValue changed the thrown map: %s", custom.Inspect())
+	}
+
+	caught := NewErrorValue(RuntimeErrorKind, "division by zero", nil)
+	if ThrownErrorValue(caught, []string{"f"}) != caught {
+		t.Errorf("ThrownErrorValue did not rethrow a complete error value unchanged")
+	}
+
+	for _, tc := range []struct {
+		value    IObject
+		expected string
+	}{
+		{caught, "division by zero"},
+		{custom, "m"},
+		{&String{Value: "boom"}, "boom"},
+		{&Map{Pairs: map[HashKey]MapPair{}}, "{}"},
+	} {
+		if got := ErrorMessage(tc.value); got != tc.expected {
+			t.Errorf("ErrorMessage(%s) = %q, want = %q", tc.value.Inspect(), got, tc.expected)
+		}
+	}
+}
+
+func TestCheckArity(t *testing.T) {
+	testCases := []struct {
+		argsCount, parameters, optional int
+		variadic                        bool
+		expected                        string
+	}{
+		{2, 2, 0, false, ""},
+		{1, 2, 0, false, "wrong number of arguments: got = 1, want = 2"},
+		{3, 2, 0, false, "wrong number of arguments: got = 3, want = 2"},
+		{1, 3, 2, false, ""},
+		{3, 3, 2, false, ""},
+		{0, 3, 2, false, "wrong number of arguments: got = 0, want 1 to 3"},
+		{4, 3, 2, false, "wrong number of arguments: got = 4, want 1 to 3"},
+		{5, 1, 0, true, ""},
+		{0, 0, 0, true, ""},
+		{0, 2, 1, true, "wrong number of arguments: got = 0, want at least 1"},
+	}
+
+	for _, tc := range testCases {
+		err := CheckArity(tc.argsCount, tc.parameters, tc.optional, tc.variadic)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.expected {
+			t.Errorf("CheckArity(%d, %d, %d, %t) = %q, want = %q",
+				tc.argsCount, tc.parameters, tc.optional, tc.variadic, got, tc.expected)
+		}
+	}
+}
+
+func TestSliceBounds(t *testing.T) {
+	testCases := []struct {
+		low, high  IObject
+		start, end int
+		expected   string
+	}{
+		{&Null{}, &Null{}, 0, 5, ""},
+		{&Integer{Value: 1}, &Integer{Value: 3}, 1, 3, ""},
+		{&Integer{Value: -2}, &Integer{Value: 10}, 0, 5, ""},
+		{&Integer{Value: 4}, &Integer{Value: 2}, 2, 2, ""},
+		{&Integer{Value: 2}, &Null{}, 2, 5, ""},
+		{&String{Value: "a"}, &Null{}, 0, 0, "slice bound must be INTEGER, got STRING"},
+		{&Null{}, &Float{Value: 1}, 0, 0, "slice bound must be INTEGER, got FLOAT"},
+	}
+
+	for _, tc := range testCases {
+		start, end, err := SliceBounds(tc.low, tc.high, 5)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.expected || start != tc.start || end != tc.end {
+			t.Errorf("SliceBounds(%s, %s, 5) = %d, %d, %q, want = %d, %d, %q",
+				tc.low.Inspect(), tc.high.Inspect(), start, end, got, tc.start, tc.end, tc.expected)
+		}
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	testCases := []struct {
+		value    float64
+		expected string
+	}{
+		{1.5, "1.5"},
+		{2, "2.0"},
+		{-0.25, "-0.25"},
+		{1e21, "1e+21"},
+		{6.02e-23, "6.02e-23"},
+		{math.Inf(-1), "-Inf"},
+		{math.NaN(), "NaN"},
+	}
+
+	for _, tc := range testCases {
+		got := (&Float{Value: tc.value}).Inspect()
+		if got != tc.expected {
+			t.Errorf("Float{%g}.Inspect() = %q, want = %q", tc.value, got, tc.expected)
+		}
+	}
+}
+
+func TestFloatHashKey(t *testing.T) {
+	if (&Float{Value: 2}).HashKey() != (&Integer{Value: 2}).HashKey() {
+		t.Errorf("integral float has a different hash key than the equal integer")
+	}
+	if (&Float{Value: 0.5}).HashKey() != (&Float{Value: 0.5}).HashKey() {
+		t.Errorf("floats with thesame value have different hash keys")
+	}
+	if (&Float{Value: 0.5}).HashKey() == (&Float{Value: 1.5}).HashKey() {
+		t.Errorf("floats with different values have thesame hash keys")
+	}
+}
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"BigTalk_Interpreter/token"
+	"fmt"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Diagnostic describes a problem found while parsing.
+type Diagnostic struct {
+	Pos      token.Position
+	Severity Severity
+	Expected []token.TokenType // tokens that would have been accepted, empty when not applicable
+	Actual   token.Token       // token the parser found instead
+	Message  string
+}
+
+// String formats the diagnostic as "file:line:column: message".
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Pos, d.Message)
+}
+
+// expectedList joins token types for an error message, e.g. "IDENT, ; or }".
+func expectedList(types []token.TokenType) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = string(t)
+	}
+	if len(names) <= 1 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
@@ -5,12 +5,16 @@ import (
 	"BigTalk_Interpreter/lexer"
 	"BigTalk_Interpreter/token"
 	"fmt"
+	"sort"
 	"strconv"
 )
 
 const (
 	_ int = iota
 	LOWEST
+	ASSIGNMENT // = += -= *= /= %=
+	OR         // ||
+	AND        // &&
 	EQUALS
 	LESSGREATER
 	SUM
@@ -26,21 +30,43 @@ type (
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:            EQUALS,
-	token.NOT_EQ:        EQUALS,
-	token.LT:            LESSGREATER,
-	token.GT:            LESSGREATER,
-	token.PLUS:          SUM,
-	token.MINUS:         SUM,
-	token.SLASH:         PRODUCT,
-	token.ASTERISK:      PRODUCT,
-	token.LPAREN:        CALL,
-	token.L_SQR_BRACKET: INDEX,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.ASSIGN:          ASSIGNMENT,
+	token.PLUS_ASSIGN:     ASSIGNMENT,
+	token.MINUS_ASSIGN:    ASSIGNMENT,
+	token.ASTERISK_ASSIGN: ASSIGNMENT,
+	token.SLASH_ASSIGN:    ASSIGNMENT,
+	token.PERCENT_ASSIGN:  ASSIGNMENT,
+	token.OR:              OR,
+	token.AND:             AND,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.LT_EQ:           LESSGREATER,
+	token.GT_EQ:           LESSGREATER,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.SLASH:           PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.PERCENT:         PRODUCT,
+	token.LPAREN:          CALL,
+	token.L_SQR_BRACKET:   INDEX,
 }
 
 type Parser struct {
-	lexer  *lexer.Lexer
-	errors []string
+	lexer       *lexer.Lexer
+	diagnostics []Diagnostic
+	lexerErrors int // number of lexer errors already turned into diagnostics
+
+	// recovering is set once a statement failed to parse. Further errors are suppressed
+	// until the parser synchronizes on the end of that statement.
+	recovering bool
+	braceDepth int // number of `{` left open up to and including currentToken
+	loopDepth  int // number of loop bodies enclosing the current statement, reset by function bodies
+	// inValue is set while parsing an expression whose value is used, such as an operand or
+	// an array item. A break or continue there would leave the value half computed.
+	inValue   bool
+	loopJumps int // number of break and continue statements parsed so far
 
 	currentToken token.Token
 	peekToken    token.Token
@@ -51,21 +77,26 @@ type Parser struct {
 
 func NewParser(l *lexer.Lexer) *Parser {
 	p := &Parser{
-		lexer:  l,
-		errors: []string{},
+		lexer:       l,
+		diagnostics: []Diagnostic{},
 	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
+	p.registerPrefix(token.NULL, p.parseNullLiteral)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.STRING_HEAD, p.parseInterpolatedString)
 	p.registerPrefix(token.L_SQR_BRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseMapLiteral)
 
@@ -74,10 +105,21 @@ func NewParser(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PERCENT_ASSIGN, p.parseAssignExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.L_SQR_BRACKET, p.parseIndexExpression)
 
@@ -87,18 +129,107 @@ func NewParser(l *lexer.Lexer) *Parser {
 	return p
 }
 
+// Errors returns the diagnostics formatted as "file:line:column: message".
 func (p *Parser) Errors() []string {
-	return p.errors
+	errors := make([]string, len(p.diagnostics))
+	for i, d := range p.Diagnostics() {
+		errors[i] = d.String()
+	}
+	return errors
+}
+
+// Diagnostics returns every problem found while lexing and parsing, in source order. The
+// slice is a copy the caller is free to modify.
+func (p *Parser) Diagnostics() []Diagnostic {
+	diagnostics := append([]Diagnostic(nil), p.diagnostics...)
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Pos.Offset < diagnostics[j].Pos.Offset
+	})
+	return diagnostics
+}
+
+// addError records an error about the actual token and puts the parser into recovery.
+// Errors raised while already recovering are follow-ups of the first one and are dropped.
+func (p *Parser) addError(actual token.Token, expected []token.TokenType, format string, a ...any) {
+	if p.recovering {
+		return
+	}
+	p.recovering = true
+	if actual.Type == token.ILLEGAL && p.reportedByLexer(actual.Pos) {
+		// The lexer already explained what is wrong, e.g. an unterminated string.
+		return
+	}
+
+	p.diagnostics = append(p.diagnostics, Diagnostic{
+		Pos:      actual.Pos,
+		Severity: SeverityError,
+		Expected: expected,
+		Actual:   actual,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+// addLexerErrors records the errors the lexer found since the last call. They do not
+// disturb the parser, which never sees the offending input as tokens.
+func (p *Parser) addLexerErrors() {
+	errors := p.lexer.Errors()
+	for _, err := range errors[p.lexerErrors:] {
+		p.diagnostics = append(p.diagnostics, Diagnostic{
+			Pos:      err.Pos,
+			Severity: SeverityError,
+			Actual:   token.Token{Type: token.ILLEGAL, Pos: err.Pos},
+			Message:  err.Message,
+		})
+	}
+	p.lexerErrors = len(errors)
 }
 
-func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+func (p *Parser) peekError(expected ...token.TokenType) {
+	p.addError(p.peekToken, expected, "expected next token to be %s, got %s instead", expectedList(expected), p.peekToken.Type)
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function registered for %s token", t)
-	p.errors = append(p.errors, msg)
+	p.addError(p.currentToken, nil, "no prefix parse function registered for %s token", t)
+}
+
+// reportedByLexer reports whether a lexer error was recorded at pos.
+func (p *Parser) reportedByLexer(pos token.Position) bool {
+	for _, err := range p.lexer.Errors() {
+		if err.Pos.Offset == pos.Offset {
+			return true
+		}
+	}
+	return false
+}
+
+// statementKeywords are the keywords a statement starts with, synchronize stops in front of
+// them.
+var statementKeywords = map[token.TokenType]bool{
+	token.LET:      true,
+	token.RETURN:   true,
+	token.IF:       true,
+	token.WHILE:    true,
+	token.FOR:      true,
+	token.BREAK:    true,
+	token.CONTINUE: true,
+	token.THROW:    true,
+}
+
+// synchronize skips the rest of a statement that failed to parse. depth is the brace depth
+// of the enclosing block body. Nested braces are skipped as a whole and the parser stops on
+// the `;` ending the statement, right before a keyword starting the next statement, right
+// before the `}` closing the enclosing block or on that `}` when the failing token was the
+// closing brace itself.
+func (p *Parser) synchronize(depth int) {
+	for !p.currentTokenIs(token.EOF) && p.braceDepth >= depth {
+		if p.braceDepth == depth &&
+			(p.currentTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) ||
+				statementKeywords[p.peekToken.Type]) {
+			break
+		}
+		p.nextToken()
+	}
+	p.recovering = false
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -106,7 +237,11 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.IStatement{}
 	for p.currentToken.Type != token.EOF {
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.recovering {
+			p.synchronize(0)
+			// A stray `}` at the top level does not close anything.
+			p.braceDepth = max(p.braceDepth, 0)
+		} else if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
 		p.nextToken()
@@ -114,15 +249,36 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+// parseStatement returns nil when the statement could not be parsed.
 func (p *Parser) parseStatement() ast.IStatement {
+	var stmt ast.IStatement
 	switch p.currentToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		if let := p.parseLetStatement(); let != nil {
+			stmt = let
+		}
 	case token.RETURN:
-		return p.parseReturnStatement()
+		stmt = p.parseReturnStatement()
+	case token.WHILE:
+		if while := p.parseWhileStatement(); while != nil {
+			stmt = while
+		}
+	case token.FOR:
+		if loop := p.parseForStatement(); loop != nil {
+			stmt = loop
+		}
+	case token.BREAK:
+		stmt = p.parseBreakStatement()
+	case token.CONTINUE:
+		stmt = p.parseContinueStatement()
+	case token.THROW:
+		if throw := p.parseThrowStatement(); throw != nil {
+			stmt = throw
+		}
 	default:
-		return p.parseExpressionStatement()
+		stmt = p.parseExpressionStatement()
 	}
+	return stmt
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
@@ -132,7 +288,7 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
-	if p.peekTokenIs(token.SEMICOLON) {
+	if !p.recovering && p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
@@ -145,20 +301,40 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 // Returns:
 // *ast.LetStatement - A pointer to the Let statement AST node.
 //
-// This function creates a new LetStatement node with the current token. It then expects the next token to be an IDENT token,
-// returns nil if it is not and sets the Name field of the LetStatement to an Identifier node with the value of the current token.
-// It then expects the next token to be an ASSIGN token, returns nil if it is not and advances the parser to the next token.
-// It then calls parseExpression with a precedence of LOWEST to parse the value expression of the Let statement and assigns the result
-// to the Value field of the LetStatement node.
+// This function calls parseLetBinding to parse the binding, which is either `let <ident> = <expr>` or a destructuring
+// `let <pattern> = <expr>`. If the next token is a `[` or a `{`, the Pattern field of the LetStatement is set to the
+// array or map pattern parsed by parsePattern, otherwise the next token must be an IDENT token, which sets the Name field.
+// It then expects an ASSIGN token and parses the value expression with a precedence of LOWEST into the Value field.
+// It returns nil if any of these steps fails.
 // Finally, it consumes any optional SEMICOLON tokens and returns the LetStatement node.
 func (p *Parser) parseLetStatement() *ast.LetStatement {
-	stmt := &ast.LetStatement{Token: p.currentToken}
-
-	if !p.expectPeek(token.IDENT) {
+	stmt := p.parseLetBinding()
+	if stmt == nil {
 		return nil
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	for !p.recovering && p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseLetBinding parses `let <name> = <value>` or `let <pattern> = <value>` and leaves the
+// parser on the last token of the value, the terminating semicolon is up to the caller.
+func (p *Parser) parseLetBinding() *ast.LetStatement {
+	stmt := &ast.LetStatement{Token: p.currentToken}
+
+	if p.peekTokenIs(token.L_SQR_BRACKET) || p.peekTokenIs(token.LBRACE) {
+		stmt.Pattern = p.parsePattern()
+		if stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	}
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -168,11 +344,264 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
-	if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+	if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok && stmt.Name != nil {
 		fn.Name = stmt.Name.Value
 	}
+	return stmt
+}
+
+// parsePattern parses the destructuring pattern starting at the next token, an identifier,
+// an array pattern or a map pattern, and leaves the parser on its last token.
+func (p *Parser) parsePattern() ast.IExpression {
+	switch {
+	case p.peekTokenIs(token.IDENT):
+		p.nextToken()
+		return &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	case p.peekTokenIs(token.L_SQR_BRACKET):
+		p.nextToken()
+		return p.parseArrayPattern(p.parsePattern, true)
+	case p.peekTokenIs(token.LBRACE):
+		p.nextToken()
+		return p.parseMapPattern(p.parsePattern, true)
+	default:
+		p.peekError(token.IDENT, token.L_SQR_BRACKET, token.LBRACE)
+		return nil
+	}
+}
+
+// parseArrayPattern parses an array pattern starting on its `[`. parseTarget parses the
+// pattern of an element starting at the next token, elements may have a default value
+// if withDefaults is set.
+func (p *Parser) parseArrayPattern(parseTarget func() ast.IExpression, withDefaults bool) ast.IExpression {
+	pattern := &ast.ArrayPattern{Token: p.currentToken}
+
+	for !p.peekTokenIs(token.R_SQR_BRACKET) {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+			if !p.peekTokenIs(token.R_SQR_BRACKET) {
+				p.addError(p.peekToken, []token.TokenType{token.R_SQR_BRACKET}, "rest element must be the last element of the pattern")
+				return nil
+			}
+			break
+		}
+
+		element := &ast.PatternElement{Target: parseTarget()}
+		if element.Target == nil || withDefaults && !p.parsePatternDefault(element) {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.R_SQR_BRACKET) {
+		return nil
+	}
+	pattern.RBracket = p.currentToken
+	return pattern
+}
+
+// parseMapPattern parses a map pattern starting on its `{` like parseArrayPattern does.
+func (p *Parser) parseMapPattern(parseTarget func() ast.IExpression, withDefaults bool) ast.IExpression {
+	pattern := &ast.MapPattern{Token: p.currentToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.peekTokenIs(token.IDENT) && !p.peekTokenIs(token.STRING) {
+			p.peekError(token.IDENT, token.STRING)
+			return nil
+		}
+		p.nextToken()
+
+		element := &ast.PatternElement{Key: &ast.StringLiteral{Token: p.currentToken, Value: p.currentToken.Literal}}
+		switch {
+		case p.peekTokenIs(token.COLON):
+			p.nextToken()
+			element.Target = parseTarget()
+			if element.Target == nil {
+				return nil
+			}
+		case p.currentTokenIs(token.IDENT):
+			element.Target = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+		default:
+			// A string key does not name a binding, the target has to be spelled out.
+			p.peekError(token.COLON)
+			return nil
+		}
+		if withDefaults && !p.parsePatternDefault(element) {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	pattern.RBrace = p.currentToken
+	return pattern
+}
+
+// parsePatternDefault parses the `= <default>` following a pattern element, if there is one.
+func (p *Parser) parsePatternDefault(element *ast.PatternElement) bool {
+	if !p.peekTokenIs(token.ASSIGN) {
+		return true
+	}
+	p.nextToken()
+	p.nextToken()
+
+	element.Default = p.parseExpression(LOWEST)
+	return element.Default != nil
+}
+
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.currentToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
 
-	for p.peekTokenIs(token.SEMICOLON) {
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if !p.recovering && p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseForStatement parses `for (init; condition; post) { body }`. Each of the three
+// clauses may be left empty, init and post are either a let binding or an expression.
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.currentToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		stmt.Init = p.parseForClause()
+	}
+	if !p.expectPeek(token.SEMICOLON) {
+		return nil
+	}
+
+	if !p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		stmt.Condition = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(token.SEMICOLON) {
+		return nil
+	}
+
+	if !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		stmt.Post = p.parseForClause()
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if !p.recovering && p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseForClause parses the init or post clause of a for loop without consuming the
+// semicolon or parenthesis that follows it.
+func (p *Parser) parseForClause() ast.IStatement {
+	if p.currentTokenIs(token.LET) {
+		if let := p.parseLetBinding(); let != nil {
+			return let
+		}
+		return nil
+	}
+	return &ast.ExpressionStatement{Token: p.currentToken, Value: p.parseExpression(LOWEST)}
+}
+
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	inValue := p.inValue
+	p.loopDepth++
+	p.inValue = false
+	defer func() {
+		p.loopDepth--
+		p.inValue = inValue
+	}()
+	return p.parseBlockStatement()
+}
+
+// checkLoopJump reports a break or continue on currentToken that has no loop to leave or
+// that would leave an expression whose value is used.
+func (p *Parser) checkLoopJump() {
+	switch {
+	case p.loopDepth == 0:
+		p.addError(p.currentToken, nil, "%s outside of a loop", p.currentToken.Literal)
+	case p.inValue:
+		p.addError(p.currentToken, nil, "%s inside an expression whose value is used", p.currentToken.Literal)
+	default:
+		p.loopJumps++
+	}
+}
+
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.currentToken}
+	p.checkLoopJump()
+
+	if !p.recovering && p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.currentToken}
+	p.checkLoopJump()
+
+	if !p.recovering && p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.currentToken}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+	if stmt.Value == nil {
+		return nil
+	}
+
+	if !p.recovering && p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
@@ -205,6 +634,14 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 func (p *Parser) nextToken() {
 	p.currentToken = p.peekToken
 	p.peekToken = p.lexer.NextToken()
+	p.addLexerErrors()
+
+	switch p.currentToken.Type {
+	case token.LBRACE:
+		p.braceDepth++
+	case token.RBRACE:
+		p.braceDepth--
+	}
 }
 
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
@@ -215,23 +652,39 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
+// parseExpressionStatement parses an expression standing on its own. Its value is not used,
+// so an if, match or try expression here may still break or continue the enclosing loop,
+// as long as no operator is applied to it.
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.currentToken}
-	stmt.Value = p.parseExpression(LOWEST)
+	stmt.Value = p.parseOperators(LOWEST)
 
-	if p.peekTokenIs(token.SEMICOLON) {
+	if !p.recovering && p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
 }
 
+// parseExpression parses an expression whose value is used.
 func (p *Parser) parseExpression(precedence int) ast.IExpression {
+	inValue := p.inValue
+	p.inValue = true
+	defer func() { p.inValue = inValue }()
+
+	return p.parseOperators(precedence)
+}
+
+// parseOperators parses a prefix expression followed by the infix operators binding tighter
+// than precedence. The prefix expression is parsed in the current context, every operand
+// after it is a value.
+func (p *Parser) parseOperators(precedence int) ast.IExpression {
 	prefix := p.prefixParseFns[p.currentToken.Type]
 	if prefix == nil {
 		p.noPrefixParseFnError(p.currentToken.Type)
 		return nil
 	}
 
+	loopJumps := p.loopJumps
 	leftExp := prefix()
 
 	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
@@ -239,6 +692,11 @@ func (p *Parser) parseExpression(precedence int) ast.IExpression {
 		if infix == nil {
 			return leftExp
 		}
+		if p.loopJumps > loopJumps {
+			// The operator uses the value of an expression that breaks or continues.
+			p.addError(p.peekToken, nil, "%s cannot be applied to an expression containing break or continue", p.peekToken.Literal)
+			loopJumps = p.loopJumps
+		}
 		p.nextToken()
 		leftExp = infix(leftExp)
 	}
@@ -255,8 +713,19 @@ func (p *Parser) parseIntegerLiteral() ast.IExpression {
 
 	value, err := strconv.ParseInt(p.currentToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as a 64bit integer", p.currentToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.currentToken, nil, "could not parse %q as a 64bit integer", p.currentToken.Literal)
+		return nil
+	}
+	literal.Value = value
+	return literal
+}
+
+func (p *Parser) parseFloatLiteral() ast.IExpression {
+	literal := &ast.FloatLiteral{Token: p.currentToken}
+
+	value, err := strconv.ParseFloat(p.currentToken.Literal, 64)
+	if err != nil {
+		p.addError(p.currentToken, nil, "could not parse %q as a 64bit float", p.currentToken.Literal)
 		return nil
 	}
 	literal.Value = value
@@ -300,6 +769,34 @@ func (p *Parser) parseInfixExpression(leftValue ast.IExpression) ast.IExpression
 	return exp
 }
 
+// parseAssignExpression parses `target = value` and the compound assignments. They are right
+// associative, `a = b = 1` assigns 1 to b and then to a.
+func (p *Parser) parseAssignExpression(target ast.IExpression) ast.IExpression {
+	exp := &ast.AssignExpression{
+		Token:    p.currentToken,
+		Operator: p.currentToken.Literal,
+		Target:   target,
+	}
+
+	if p.recovering {
+		return nil
+	}
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		p.addError(p.currentToken, nil, "cannot assign to %s", target.String())
+		return nil
+	}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(ASSIGNMENT - 1)
+	return exp
+}
+
+func (p *Parser) parseNullLiteral() ast.IExpression {
+	return &ast.NullLiteral{Token: p.currentToken}
+}
+
 func (p *Parser) parseBoolean() ast.IExpression {
 	return &ast.Boolean{Token: p.currentToken, Value: p.currentTokenIs(token.TRUE)}
 }
@@ -314,64 +811,235 @@ func (p *Parser) parseGroupedExpression() ast.IExpression {
 	return exp
 }
 
-// parseIfExpression parses an if expression in the input and returns an ast.IExpression representing the parsed expression.
-// It creates an ast.IfExpression object with the current token as the token attribute.
-// If the next token is not token.LPAREN, it returns nil.
-// It advances the parser to the next token.
-// It calls parseExpression with the LOWEST precedence level to parse the condition expression and assigns it to the Condition attribute of the IfExpression object.
-// If the next token is not token.RPAREN, it returns nil.
-// If the next token is not token.LBRACE, it returns nil.
-// It calls parseBlockStatement to parse the block statement and assigns it to the Consequence attribute of the IfExpression object.
-// If the next token is token.ELSE, it advances the parser to the next token.
-// If the next token is not token.LBRACE, it returns nil.
-// It calls parseBlockStatement to parse the alternative block statement and assigns it to the Alternative attribute of the IfExpression object.
-// It returns the IfExpression object.
-// ast.IExpression is the interface implemented by all types of expressions in the abstract syntax tree.
+// parseIfExpression parses an if expression with any number of `else if` clauses and an
+// optional final else block.
 func (p *Parser) parseIfExpression() ast.IExpression {
 	expression := &ast.IfExpression{Token: p.currentToken}
 
-	if !p.expectPeek(token.LPAREN) {
+	expression.Condition, expression.Consequence = p.parseConditionalBlock()
+	if expression.Consequence == nil {
 		return nil
 	}
 
-	p.nextToken()
-	expression.Condition = p.parseExpression(LOWEST)
+	for p.peekTokenIs(token.ELSE) {
+		p.nextToken()
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			elseIf := &ast.ElseIf{Token: p.currentToken}
+			elseIf.Condition, elseIf.Consequence = p.parseConditionalBlock()
+			if elseIf.Consequence == nil {
+				return nil
+			}
+			expression.ElseIfs = append(expression.ElseIfs, elseIf)
+			continue
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Alternative = p.parseBlockStatement()
+		break
 	}
+	return expression
+}
+
+func (p *Parser) parseTryExpression() ast.IExpression {
+	expression := &ast.TryExpression{Token: p.currentToken}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
+	expression.Block = p.parseBlockStatement()
 
-	expression.Consequence = p.parseBlockStatement()
-
-	if p.peekTokenIs(token.ELSE) {
+	if p.peekTokenIs(token.CATCH) {
 		p.nextToken()
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			expression.CatchParam = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		expression.Catch = p.parseBlockStatement()
+	}
+
+	if expression.Catch == nil && !p.peekTokenIs(token.FINALLY) {
+		p.peekError(token.CATCH, token.FINALLY)
+		return nil
+	}
 
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
+		expression.Finally = p.parseBlockStatement()
+	}
+	return expression
+}
 
-		expression.Alternative = p.parseBlockStatement()
+func (p *Parser) parseMatchExpression() ast.IExpression {
+	expression := &ast.MatchExpression{Token: p.currentToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expression.Arms = append(expression.Arms, arm)
+
+		// An arm with a block body does not need a comma after it.
+		_, isBlock := arm.Body.(*ast.BlockStatement)
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !isBlock {
+			break
+		}
 	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	expression.RBrace = p.currentToken
 	return expression
 }
 
+// parseMatchArm parses `<pattern> if <guard> => <body>` starting at the next token and leaves
+// the parser on the last token of the body.
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Pattern: p.parseMatchPattern()}
+	if arm.Pattern == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		arm.Guard = p.parseExpression(LOWEST)
+		if arm.Guard == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		arm.Body = p.parseBlockStatement()
+		return arm
+	}
+
+	p.nextToken()
+	body := p.parseExpression(LOWEST)
+	if body == nil {
+		return nil
+	}
+	arm.Body = body
+	return arm
+}
+
+// parseMatchPattern parses the pattern of a match arm starting at the next token: a literal,
+// an identifier binding the value, which the wildcard `_` does not, or an array or map
+// pattern of further patterns.
+func (p *Parser) parseMatchPattern() ast.IExpression {
+	switch {
+	case p.peekTokenIs(token.L_SQR_BRACKET):
+		p.nextToken()
+		return p.parseArrayPattern(p.parseMatchPattern, false)
+	case p.peekTokenIs(token.LBRACE):
+		p.nextToken()
+		return p.parseMapPattern(p.parseMatchPattern, false)
+	case p.peekTokenIs(token.MINUS):
+		p.nextToken()
+		expression := &ast.PrefixExpression{Token: p.currentToken, Operator: p.currentToken.Literal}
+		if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.FLOAT) {
+			p.peekError(token.INT, token.FLOAT)
+			return nil
+		}
+		p.nextToken()
+		expression.Value = p.prefixParseFns[p.currentToken.Type]()
+		if expression.Value == nil {
+			return nil
+		}
+		return expression
+	}
+
+	for _, literal := range []token.TokenType{token.IDENT, token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE, token.NULL} {
+		if p.peekTokenIs(literal) {
+			p.nextToken()
+			return p.prefixParseFns[literal]()
+		}
+	}
+
+	p.addError(p.peekToken, nil, "expected a pattern, got %s instead", p.peekToken.Type)
+	return nil
+}
+
+// parseConditionalBlock parses the `(<condition>) { ... }` following an `if`. The block is
+// nil when either part is missing.
+func (p *Parser) parseConditionalBlock() (ast.IExpression, *ast.BlockStatement) {
+	if !p.expectPeek(token.LPAREN) {
+		return nil, nil
+	}
+
+	p.nextToken()
+	condition := p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil, nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil, nil
+	}
+
+	return condition, p.parseBlockStatement()
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.currentToken}
 	block.Statements = []ast.IStatement{}
+	depth := p.braceDepth
 
 	p.nextToken()
 
 	for !p.currentTokenIs(token.RBRACE) && !p.currentTokenIs(token.EOF) {
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.recovering {
+			p.synchronize(depth)
+			// The failing token may have been the closing brace itself.
+			if p.braceDepth < depth {
+				break
+			}
+		} else if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
 	}
+
+	if p.currentTokenIs(token.EOF) {
+		p.addError(p.currentToken, []token.TokenType{token.RBRACE}, "expected %s to close the block, got %s instead", token.RBRACE, token.EOF)
+	}
+	block.RBrace = p.currentToken
 	return block
 }
 
@@ -379,7 +1047,9 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 // It creates a new ast.FunctionLiteral with the current token as the Token field.
 // It expects the next token to be of type token.LPAREN and advances the parser to the next token if it is.
 // If the next token is not token.LPAREN, it returns nil.
-// It then calls parseFunctionParameters to parse the function parameters and assigns the result to the Parameters field of fnLit.
+// It then calls parseFunctionParameters to parse the function parameters into fnLit: the names go to the Parameters field,
+// their default values, nil for a parameter without one, to the Defaults field and a trailing `...rest` parameter to the Rest field.
+// If the parameters cannot be parsed, it returns nil.
 // It expects the next token to be of type token.LBRACE and advances the parser to the next token if it is.
 // If the next token is not token.LBRACE, it returns nil.
 // Finally, it calls parseBlockStatement to parse the function body and assigns the result to the Body field of fnLit.
@@ -391,59 +1061,83 @@ func (p *Parser) parseFunctionLiteral() ast.IExpression {
 		return nil
 	}
 
-	fnLit.Parameters = p.parseFunctionParameters()
+	if !p.parseFunctionParameters(fnLit) {
+		return nil
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
+	// `break` and `continue` cannot leave the function they are written in.
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
 	fnLit.Body = p.parseBlockStatement()
+	p.loopDepth = loopDepth
 	return fnLit
 }
 
-// parseFunctionParameters parses the function parameters and returns a slice of ast.Identifier pointers.
-// If there are no parameters, it returns an empty slice.
-//
-// It first checks if the next token is `)` and advances the parser if true.
-// Then it advances the parser to the next token.
-// It creates a new ast.Identifier with the current token's information and appends it to the identifiers slice.
-//
-// The function continues the loop as long as the next token is `,`.
-// Inside the loop, it advances the parser twice to skip the comma and the next token.
-// It creates a new ast.Identifier with the current token's information and appends it to the identifiers slice.
-//
-// Finally, it checks if the next token is `)` using the expectPeek method.
-// If it is not `)`, it returns nil. Otherwise, it returns the identifiers slice.
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	var identifiers []*ast.Identifier
-
+// parseFunctionParameters parses the parameter list of fnLit, starting on its `(` and ending
+// on its `)`. A parameter is either a name, a name with a default value such as `b = 2`, or
+// a trailing rest parameter such as `...rest`. Once a parameter has a default value every
+// following one needs one too. It returns false when the list could not be parsed.
+func (p *Parser) parseFunctionParameters(fnLit *ast.FunctionLiteral) bool {
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return true
 	}
 
-	p.nextToken()
+	for {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			fnLit.Rest = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+			if !p.peekTokenIs(token.RPAREN) {
+				p.addError(p.peekToken, []token.TokenType{token.RPAREN}, "rest parameter must be the last parameter")
+				return false
+			}
+			break
+		}
 
-	ident := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
-	identifiers = append(identifiers, ident)
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
+		ident := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
 
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
-		p.nextToken()
+		var value ast.IExpression
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			value = p.parseExpression(LOWEST)
+		} else if len(fnLit.Defaults) > 0 && fnLit.Defaults[len(fnLit.Defaults)-1] != nil {
+			p.addError(ident.Token, nil, "parameter %s needs a default value, it follows a parameter with one", ident.Value)
+			return false
+		}
 
-		ident := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
-		identifiers = append(identifiers, ident)
+		fnLit.Parameters = append(fnLit.Parameters, ident)
+		fnLit.Defaults = append(fnLit.Defaults, value)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
 	}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+	if !p.peekTokenIs(token.RPAREN) {
+		p.peekError(token.COMMA, token.RPAREN)
+		return false
 	}
-	return identifiers
+	p.nextToken()
+	return true
 }
 
 func (p *Parser) parseCallExpression(function ast.IExpression) ast.IExpression {
 	exp := &ast.CallExpression{Token: p.currentToken, Func: function}
 	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.RParen = p.currentToken
 	return exp
 }
 
@@ -488,9 +1182,52 @@ func (p *Parser) parseStringLiteral() ast.IExpression {
 	return &ast.StringLiteral{Token: p.currentToken, Value: p.currentToken.Literal}
 }
 
+// parseInterpolatedString parses the segments of an interpolated string, starting on its
+// STRING_HEAD token and ending on its STRING_TAIL token.
+func (p *Parser) parseInterpolatedString() ast.IExpression {
+	str := &ast.InterpolatedString{Token: p.currentToken}
+	p.appendStringPart(str)
+
+	for {
+		if p.peekTokenIs(token.STRING_MID) || p.peekTokenIs(token.STRING_TAIL) {
+			p.addError(p.peekToken, nil, "empty expression in string interpolation")
+			return nil
+		}
+		p.nextToken()
+		part := p.parseExpression(LOWEST)
+		if part == nil {
+			return nil
+		}
+		str.Parts = append(str.Parts, part)
+
+		switch {
+		case p.peekTokenIs(token.STRING_MID):
+			p.nextToken()
+			p.appendStringPart(str)
+		case p.peekTokenIs(token.STRING_TAIL):
+			p.nextToken()
+			p.appendStringPart(str)
+			str.Tail = p.currentToken
+			return str
+		default:
+			expected := []token.TokenType{token.STRING_MID, token.STRING_TAIL}
+			p.addError(p.peekToken, expected, "expected } to close the string interpolation, got %s instead", p.peekToken.Type)
+			return nil
+		}
+	}
+}
+
+// appendStringPart adds the text of the current string segment to str unless it is empty.
+func (p *Parser) appendStringPart(str *ast.InterpolatedString) {
+	if p.currentToken.Literal != "" {
+		str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.currentToken, Value: p.currentToken.Literal})
+	}
+}
+
 func (p *Parser) parseArrayLiteral() ast.IExpression {
 	array := &ast.ArrayLiteral{Token: p.currentToken}
 	array.Items = p.parseExpressionList(token.R_SQR_BRACKET)
+	array.RBracket = p.currentToken
 	return array
 }
 
@@ -515,30 +1252,74 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.IExpression {
 	}
 
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	list = append(list, p.parseListItem())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
+		list = append(list, p.parseListItem())
 	}
 
-	if !p.expectPeek(end) {
+	if !p.peekTokenIs(end) {
+		p.peekError(token.COMMA, end)
 		return nil
 	}
+	p.nextToken()
 
 	return list
 }
 
+// parseListItem parses one item of an expression list, either an expression or an
+// expression spread with `...`.
+func (p *Parser) parseListItem() ast.IExpression {
+	if !p.currentTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+
+	spread := &ast.SpreadElement{Token: p.currentToken}
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+	if spread.Value == nil {
+		return nil
+	}
+	return spread
+}
+
 func (p *Parser) parseIndexExpression(left ast.IExpression) ast.IExpression {
 	exp := &ast.IndexExpression{Token: p.currentToken, Left: left}
+	if p.peekTokenIs(token.COLON) {
+		return p.parseSliceExpression(exp.Token, left, nil)
+	}
 	p.nextToken()
 
 	exp.Index = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.COLON) {
+		return p.parseSliceExpression(exp.Token, left, exp.Index)
+	}
+
+	if !p.expectPeek(token.R_SQR_BRACKET) {
+		return nil
+	}
+	exp.RBracket = p.currentToken
+
+	return exp
+}
+
+// parseSliceExpression parses the rest of `left[low:high]` with the colon as the next token.
+// Either bound may be left out, low is nil then.
+func (p *Parser) parseSliceExpression(lbracket token.Token, left, low ast.IExpression) ast.IExpression {
+	exp := &ast.SliceExpression{Token: lbracket, Left: left, Low: low}
+	p.nextToken()
+
+	if !p.peekTokenIs(token.R_SQR_BRACKET) {
+		p.nextToken()
+		exp.High = p.parseExpression(LOWEST)
+	}
 
 	if !p.expectPeek(token.R_SQR_BRACKET) {
 		return nil
 	}
+	exp.RBracket = p.currentToken
 
 	return exp
 }
@@ -573,7 +1354,10 @@ func (p *Parser) parseMapLiteral() ast.IExpression {
 		value := p.parseExpression(LOWEST)
 
 		mapLit.Pairs[key] = value
-		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.peekTokenIs(token.RBRACE) {
+			p.peekError(token.COMMA, token.RBRACE)
 			return nil
 		}
 	}
@@ -581,5 +1365,6 @@ func (p *Parser) parseMapLiteral() ast.IExpression {
 	if !p.expectPeek(token.RBRACE) {
 		return nil
 	}
+	mapLit.RBrace = p.currentToken
 	return mapLit
 }
@@ -3,10 +3,877 @@ package parser
 import (
 	"BigTalk_Interpreter/ast"
 	"BigTalk_Interpreter/lexer"
+	"BigTalk_Interpreter/token"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 )
 
+func TestParsingTryExpression(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"try { f() } catch (e) { e }", "try f() catch (e) e"},
+		{"try { f() } catch { 0 } finally { g() }", "try f() catch 0 finally g()"},
+		{"try { f() } finally { g() }", "try f() finally g()"},
+		{"let x = try { 1 } catch (e) { 2 } + 1;", "let x = (try 1 catch (e) 2 + 1);"},
+		{"throw \"boom\"; throw {\"kind\": k}", "throw boom;throw {kind:k};"},
+	}
+
+	for _, tc := range testCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("program.String() = %q, want = %q", program.String(), tc.expected)
+		}
+	}
+
+	p := NewParser(lexer.NewLexer("try { a } catch (err) { b } finally { c }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	try, ok := program.Statements[0].(*ast.ExpressionStatement).Value.(*ast.TryExpression)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not a try expression. got = %s", program.Statements[0])
+	}
+	testIdentifier(t, try.Block.Statements[0].(*ast.ExpressionStatement).Value, "a")
+	testIdentifier(t, try.CatchParam, "err")
+	testIdentifier(t, try.Catch.Statements[0].(*ast.ExpressionStatement).Value, "b")
+	testIdentifier(t, try.Finally.Statements[0].(*ast.ExpressionStatement).Value, "c")
+	if try.End() != try.Finally.End() {
+		t.Errorf("try.End() = %s, want = %s", try.End(), try.Finally.End())
+	}
+
+	errorCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"try { 1 }", []string{"1:10: expected next token to be CATCH or FINALLY, got EOF instead"}},
+		{"try 1 catch (e) {}", []string{"1:5: expected next token to be {, got INT instead"}},
+		{"try {} catch (1) {}", []string{"1:15: expected next token to be IDENT, got INT instead"}},
+		{"try {} catch e {}", []string{"1:14: expected next token to be {, got IDENT instead"}},
+		{"throw;", []string{"1:6: no prefix parse function registered for ; token"}},
+	}
+
+	for _, tc := range errorCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		p.ParseProgram()
+		if !reflect.DeepEqual(p.Errors(), tc.expected) {
+			t.Errorf("%s: p.Errors() = %q, want = %q", tc.input, p.Errors(), tc.expected)
+		}
+	}
+}
+
+func TestParsingMatchExpression(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { 0 => a, _ => b }", "match x {0 => a, _ => b}"},
+		{"match (x) { -1 => a, 2.5 => b, \"s\" => c, true => d, null => e, }", "match x {(-1) => a, 2.5 => b, s => c, true => d, null => e}"},
+		{"match (x) { [h, ...t] if h > 0 => t }", "match x {[h, ...t] if (h > 0) => t}"},
+		{`match (x) { {"type": "a", v: [_, 1]} => v }`, "match x {{type: a, v: [_, 1]} => v}"},
+		{"match (x) { n => { let y = n; y } _ => 0 }", "match x {n => let y = n;y, _ => 0}"},
+		{"match (f(x)) {}", "match f(x) {}"},
+		{"let r = match (x) { 1 => 2 } + 1;", "let r = (match x {1 => 2} + 1);"},
+	}
+
+	for _, tc := range testCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("program.String() = %q, want = %q", program.String(), tc.expected)
+		}
+	}
+
+	p := NewParser(lexer.NewLexer("match (v) { [a] if a => 1, _ => { 2 } }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	match, ok := program.Statements[0].(*ast.ExpressionStatement).Value.(*ast.MatchExpression)
+	if !ok || len(match.Arms) != 2 {
+		t.Fatalf("program.Statements[0] is not a match with 2 arms. got = %s", program.Statements[0])
+	}
+	testIdentifier(t, match.Subject, "v")
+	if _, ok := match.Arms[0].Pattern.(*ast.ArrayPattern); !ok {
+		t.Errorf("match.Arms[0].Pattern is not *ast.ArrayPattern. got = %T", match.Arms[0].Pattern)
+	}
+	testIdentifier(t, match.Arms[0].Guard, "a")
+	testLiteralExpression(t, match.Arms[0].Body.(ast.IExpression), 1)
+	if !ast.IsWildcard(match.Arms[1].Pattern) || match.Arms[1].Guard != nil {
+		t.Errorf("match.Arms[1] = %s, want a wildcard arm without a guard", match.Arms[1])
+	}
+	if _, ok := match.Arms[1].Body.(*ast.BlockStatement); !ok {
+		t.Errorf("match.Arms[1].Body is not *ast.BlockStatement. got = %T", match.Arms[1].Body)
+	}
+
+	errorCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"match x { _ => 1 }", []string{"1:7: expected next token to be (, got IDENT instead"}},
+		{"match (x) { x + 1 => 1 }", []string{"1:15: expected next token to be =>, got + instead"}},
+		{"match (x) { (1) => 1 }", []string{"1:13: expected a pattern, got ( instead"}},
+		{"match (x) { -a => 1 }", []string{"1:14: expected next token to be INT or FLOAT, got IDENT instead"}},
+		{"match (x) { [a = 1] => 1 }", []string{"1:16: expected next token to be ], got = instead"}},
+		{"match (x) { 1 => 2 3 => 4 }", []string{"1:20: expected next token to be }, got INT instead"}},
+		{"match (x) { 1 => }", []string{"1:18: no prefix parse function registered for } token"}},
+	}
+
+	for _, tc := range errorCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		p.ParseProgram()
+		if !reflect.DeepEqual(p.Errors(), tc.expected) {
+			t.Errorf("%s: p.Errors() = %q, want = %q", tc.input, p.Errors(), tc.expected)
+		}
+	}
+}
+
+func TestParsingDestructuringLet(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = arr;", "let [a, b] = arr;"},
+		{"let [head, ...tail] = arr;", "let [head, ...tail] = arr;"},
+		{"let [a = 1, [b, c] = pair,] = arr;", "let [a = 1, [b, c] = pair] = arr;"},
+		{"let [] = arr;", "let [] = arr;"},
+		{"let {title, artist} = mus;", "let {title, artist} = mus;"},
+		{`let {title: t, "release year": year = 2000} = mus;`, "let {title: t, release year: year = 2000} = mus;"},
+		{"let {info: {year}, tags: [first]} = mus;", "let {info: {year}, tags: [first]} = mus;"},
+		{"for (let [i, j] = [0, 9]; i < j; i += 1) { i }", "for (let [i, j] = [0, 9]; (i < j); i += 1) i"},
+	}
+
+	for _, tc := range testCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("program.String() = %q, want = %q", program.String(), tc.expected)
+		}
+	}
+
+	p := NewParser(lexer.NewLexer("let {a, b: [c = 3]} = m;"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.LetStatement)
+	if stmt.Name != nil {
+		t.Errorf("stmt.Name = %s, want = nil", stmt.Name)
+	}
+	pattern, ok := stmt.Pattern.(*ast.MapPattern)
+	if !ok || len(pattern.Elements) != 2 {
+		t.Fatalf("stmt.Pattern = %T (%s), want a *ast.MapPattern with 2 elements", stmt.Pattern, stmt.Pattern)
+	}
+	if pattern.Elements[0].Key.Value != "a" {
+		t.Errorf("pattern.Elements[0].Key.Value = %q, want = %q", pattern.Elements[0].Key.Value, "a")
+	}
+	testIdentifier(t, pattern.Elements[0].Target, "a")
+	nested, ok := pattern.Elements[1].Target.(*ast.ArrayPattern)
+	if !ok || len(nested.Elements) != 1 {
+		t.Fatalf("pattern.Elements[1].Target = %T, want a *ast.ArrayPattern with 1 element", pattern.Elements[1].Target)
+	}
+	testIdentifier(t, nested.Elements[0].Target, "c")
+	testLiteralExpression(t, nested.Elements[0].Default, 3)
+	if stmt.Pattern.End().String() != "1:20" {
+		t.Errorf("stmt.Pattern.End() = %s, want = 1:20", stmt.Pattern.End())
+	}
+
+	errorCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"let [a, 1] = arr;", []string{"1:9: expected next token to be IDENT, [ or {, got INT instead"}},
+		{"let [...rest, a] = arr;", []string{"1:13: rest element must be the last element of the pattern"}},
+		{"let [a b] = arr;", []string{"1:8: expected next token to be ], got IDENT instead"}},
+		{`let {"key"} = m;`, []string{"1:11: expected next token to be :, got } instead"}},
+		{"let {[a]} = m;", []string{"1:6: expected next token to be IDENT or STRING, got [ instead"}},
+		{"let [a] 1;", []string{"1:9: expected next token to be =, got INT instead"}},
+	}
+
+	for _, tc := range errorCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		p.ParseProgram()
+		if !reflect.DeepEqual(p.Errors(), tc.expected) {
+			t.Errorf("%s: p.Errors() = %q, want = %q", tc.input, p.Errors(), tc.expected)
+		}
+	}
+}
+
+func TestParsingSpread(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"f(...args)", "f(...args)"},
+		{"f(1, ...a, ...b, 2)", "f(1, ...a, ...b, 2)"},
+		{"[...a, 1 + 2]", "[...a, (1 + 2)]"},
+		{"[...a + b]", "[...(a + b)]"},
+		{"[...f(...x)[1:]]", "[...(f(...x)[1:])]"},
+	}
+
+	for _, tc := range testCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("program.String() = %q, want = %q", program.String(), tc.expected)
+		}
+	}
+
+	p := NewParser(lexer.NewLexer("[1, ...xs]"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	array := program.Statements[0].(*ast.ExpressionStatement).Value.(*ast.ArrayLiteral)
+	spread, ok := array.Items[1].(*ast.SpreadElement)
+	if !ok {
+		t.Fatalf("array.Items[1] is not *ast.SpreadElement. got = %T", array.Items[1])
+	}
+	testIdentifier(t, spread.Value, "xs")
+	if spread.Pos().String() != "1:5" || spread.End().String() != "1:10" {
+		t.Errorf("spread spans %s-%s, want = 1:5-1:10", spread.Pos(), spread.End())
+	}
+
+	errorCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = ...a;", []string{"1:9: no prefix parse function registered for ... token"}},
+		{"f(...)", []string{"1:6: no prefix parse function registered for ) token"}},
+		{`{"a": ...b}`, []string{"1:7: no prefix parse function registered for ... token"}},
+	}
+
+	for _, tc := range errorCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		p.ParseProgram()
+		if !reflect.DeepEqual(p.Errors(), tc.expected) {
+			t.Errorf("%s: p.Errors() = %q, want = %q", tc.input, p.Errors(), tc.expected)
+		}
+	}
+}
+
+func TestParsingDefaultAndRestParameters(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(a, b = 2) { a }", "fn(a, b = 2) a"},
+		{"fn(a = 1, b = a * 2) { b }", "fn(a = 1, b = (a * 2)) b"},
+		{"fn(first, ...rest) { rest }", "fn(first, ...rest) rest"},
+		{"fn(...all) { all }", "fn(...all) all"},
+		{"fn(a = [1, 2], ...r) { a }", "fn(a = [1, 2], ...r) a"},
+	}
+
+	for _, tc := range testCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("program.String() = %q, want = %q", program.String(), tc.expected)
+		}
+	}
+
+	p := NewParser(lexer.NewLexer("fn(x, y = 2, ...z) {}"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	fnLit := stmt.Value.(*ast.FunctionLiteral)
+	if len(fnLit.Parameters) != 2 || len(fnLit.Defaults) != 2 {
+		t.Fatalf("len(fnLit.Parameters), len(fnLit.Defaults) = %d, %d, want = 2, 2", len(fnLit.Parameters), len(fnLit.Defaults))
+	}
+	testLiteralExpression(t, fnLit.Parameters[0], "x")
+	testLiteralExpression(t, fnLit.Parameters[1], "y")
+	if fnLit.Defaults[0] != nil {
+		t.Errorf("fnLit.Defaults[0] = %s, want = nil", fnLit.Defaults[0])
+	}
+	testLiteralExpression(t, fnLit.Defaults[1], 2)
+	testLiteralExpression(t, fnLit.Rest, "z")
+
+	errorCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"fn(a = 1, b) {}", []string{"1:11: parameter b needs a default value, it follows a parameter with one"}},
+		{"fn(...rest, a) {}", []string{"1:11: rest parameter must be the last parameter"}},
+		{"fn(...rest = []) {}", []string{"1:12: rest parameter must be the last parameter"}},
+		{"fn(a..) {}", []string{"1:5: expected next token to be , or ), got ILLEGAL instead"}},
+	}
+
+	for _, tc := range errorCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		p.ParseProgram()
+		if !reflect.DeepEqual(p.Errors(), tc.expected) {
+			t.Errorf("%s: p.Errors() = %q, want = %q", tc.input, p.Errors(), tc.expected)
+		}
+	}
+}
+
+func TestParsingElseIfAndNull(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"if (a) { 1 } else if (b) { 2 }", "ifa 1else ifb 2"},
+		{"if (a) { 1 } else if (b) { 2 } else if (c) { 3 } else { 4 }", "ifa 1else ifb 2else ifc 3else 4"},
+		{"let x = null;", "let x = null;"},
+		{"x == null", "(x == null)"},
+	}
+
+	for _, tc := range testCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("program.String() = %q, want = %q", program.String(), tc.expected)
+		}
+	}
+
+	p := NewParser(lexer.NewLexer("if (x < y) { x } else if (x > y) { y } else { null }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	ifExp, ok := stmt.Value.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Value is not *ast.IfExpression. got = %T", stmt.Value)
+	}
+	testInfixExpression(t, ifExp.Condition, "x", "<", "y")
+	if len(ifExp.ElseIfs) != 1 {
+		t.Fatalf("len(ifExp.ElseIfs) = %d, want = 1", len(ifExp.ElseIfs))
+	}
+	testInfixExpression(t, ifExp.ElseIfs[0].Condition, "x", ">", "y")
+	consequence := ifExp.ElseIfs[0].Consequence.Statements[0].(*ast.ExpressionStatement)
+	testIdentifier(t, consequence.Value, "y")
+
+	alternative := ifExp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if _, ok := alternative.Value.(*ast.NullLiteral); !ok {
+		t.Errorf("alternative.Value is not *ast.NullLiteral. got = %T", alternative.Value)
+	}
+	if ifExp.End().String() != "1:53" {
+		t.Errorf("ifExp.End() = %s, want = 1:53", ifExp.End())
+	}
+
+	errorCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"if (a) { 1 } else if { 2 }", []string{"1:22: expected next token to be (, got { instead"}},
+		{"if (a) { 1 } else if (b) 2", []string{"1:26: expected next token to be {, got INT instead"}},
+	}
+
+	for _, tc := range errorCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		p.ParseProgram()
+		if !reflect.DeepEqual(p.Errors(), tc.expected) {
+			t.Errorf("%s: p.Errors() = %q, want = %q", tc.input, p.Errors(), tc.expected)
+		}
+	}
+}
+
+func TestParsingSliceExpressions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"a[1:2]", "(a[1:2])"},
+		{"a[:n - 1]", "(a[:(n - 1)])"},
+		{"a[i + 1:]", "(a[(i + 1):])"},
+		{"a[:]", "(a[:])"},
+		{"a[1:][0]", "((a[1:])[0])"},
+		{`{"k": s[1:]}`, "{k:(s[1:])}"},
+	}
+
+	for _, tc := range testCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("program.String() = %q, want = %q", program.String(), tc.expected)
+		}
+	}
+
+	p := NewParser(lexer.NewLexer("arr[1:]"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	slice, ok := stmt.Value.(*ast.SliceExpression)
+	if !ok {
+		t.Fatalf("stmt.Value is not *ast.SliceExpression. got = %T", stmt.Value)
+	}
+	testIdentifier(t, slice.Left, "arr")
+	testLiteralExpression(t, slice.Low, 1)
+	if slice.High != nil {
+		t.Errorf("slice.High = %s, want = nil", slice.High)
+	}
+	if slice.End().String() != "1:8" {
+		t.Errorf("slice.End() = %s, want = 1:8", slice.End())
+	}
+
+	errorCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"a[1:2:3]", []string{"1:6: expected next token to be ], got : instead"}},
+		{"a[1:2] = 3", []string{"1:8: cannot assign to (a[1:2])"}},
+	}
+
+	for _, tc := range errorCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		p.ParseProgram()
+		if !reflect.DeepEqual(p.Errors(), tc.expected) {
+			t.Errorf("%s: p.Errors() = %q, want = %q", tc.input, p.Errors(), tc.expected)
+		}
+	}
+}
+
+func TestParsingInterpolatedStrings(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{`"Hello ${name}, you are ${age}"`, `"Hello ${name}, you are ${age}"`},
+		{`"${a + b * 2}"`, `"${(a + (b * 2))}"`},
+		{`"${ {"k": [1]}["k"][0] }!"`, `"${(({k:[1]}[k])[0])}!"`},
+		{`"outer ${"inner ${x}"}"`, `"outer ${"inner ${x}"}"`},
+		{`"${x}" + "y"`, `("${x}" + y)`},
+	}
+
+	for _, tc := range testCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("program.String() = %q, want = %q", program.String(), tc.expected)
+		}
+	}
+
+	p := NewParser(lexer.NewLexer(`"a${x}b"`))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	str, ok := stmt.Value.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("stmt.Value is not *ast.InterpolatedString. got = %T", stmt.Value)
+	}
+	if len(str.Parts) != 3 {
+		t.Fatalf("len(str.Parts) = %d, want = 3", len(str.Parts))
+	}
+	if text, ok := str.Parts[0].(*ast.StringLiteral); !ok || text.Value != "a" {
+		t.Errorf("str.Parts[0] = %s, want the text \"a\"", str.Parts[0])
+	}
+	testIdentifier(t, str.Parts[1], "x")
+	if text, ok := str.Parts[2].(*ast.StringLiteral); !ok || text.Value != "b" {
+		t.Errorf("str.Parts[2] = %s, want the text \"b\"", str.Parts[2])
+	}
+	if str.End().Column != 9 {
+		t.Errorf("str.End().Column = %d, want = 9", str.End().Column)
+	}
+
+	errorCases := []struct {
+		input    string
+		expected []string
+	}{
+		{`"a ${}"`, []string{"1:6: empty expression in string interpolation"}},
+		{`"a ${1 2}"`, []string{"1:8: expected } to close the string interpolation, got INT instead"}},
+		{`"a ${x} b`, []string{"1:7: unterminated string literal"}},
+	}
+
+	for _, tc := range errorCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		p.ParseProgram()
+		if !reflect.DeepEqual(p.Errors(), tc.expected) {
+			t.Errorf("%s: p.Errors() = %q, want = %q", tc.input, p.Errors(), tc.expected)
+		}
+	}
+}
+
+func TestParsingAssignExpressions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5;", "x = 5"},
+		{"x += 1 + 2", "x += (1 + 2)"},
+		{"a = b = c", "a = b = c"},
+		{"x %= y || z", "x %= (y || z)"},
+		{"f(x = 1)", "f(x = 1)"},
+		{`m["k"] = 1`, "(m[k]) = 1"},
+		{"a[i + 1] *= a[i]", "(a[(i + 1)]) *= (a[i])"},
+	}
+
+	for _, tc := range testCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("program.String() = %q, want = %q", program.String(), tc.expected)
+		}
+	}
+
+	p := NewParser(lexer.NewLexer("x -= 2"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	assign, ok := stmt.Value.(*ast.AssignExpression)
+	if !ok {
+		t.Fatalf("stmt.Value is not *ast.AssignExpression. got = %T", stmt.Value)
+	}
+	testIdentifier(t, assign.Target, "x")
+	testLiteralExpression(t, assign.Value, 2)
+	if assign.Operator != "-=" {
+		t.Errorf("assign.Operator = %q, want = %q", assign.Operator, "-=")
+	}
+
+	errorCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"a + b = c", []string{"1:7: cannot assign to (a + b)"}},
+		{"1 += 2", []string{"1:3: cannot assign to 1"}},
+		{"f()[0] = 1", []string{}},
+		{"f() = 1", []string{"1:5: cannot assign to f()"}},
+	}
+
+	for _, tc := range errorCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		p.ParseProgram()
+		if !reflect.DeepEqual(p.Errors(), tc.expected) {
+			t.Errorf("%s: p.Errors() = %q, want = %q", tc.input, p.Errors(), tc.expected)
+		}
+	}
+}
+
+func TestParsingLoops(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"while (x < 10) { x; }", "while ((x < 10)) x"},
+		{"while (true) { break; continue; };", "while (true) break;continue;"},
+		{"for (let i = 0; i < 3; let i = i + 1) { i }", "for (let i = 0; (i < 3); let i = (i + 1)) i"},
+		{"for (;;) { break }", "for (; ; ) break;"},
+		{"for (f(); ; g()) {}", "for (f(); ; g()) "},
+		{"while (a) { fn() { while (b) { continue } } }", "while (a) fn() while (b) continue;"},
+	}
+
+	for _, tc := range testCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%s: len(program.Statements) = %d, want = 1", tc.input, len(program.Statements))
+		}
+		if program.String() != tc.expected {
+			t.Errorf("program.String() = %q, want = %q", program.String(), tc.expected)
+		}
+	}
+
+	p := NewParser(lexer.NewLexer("for (let i = 0; i < 3;) { i }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	loop, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ForStatement. got = %T", program.Statements[0])
+	}
+	if _, ok := loop.Init.(*ast.LetStatement); !ok {
+		t.Errorf("loop.Init is not *ast.LetStatement. got = %T", loop.Init)
+	}
+	testInfixExpression(t, loop.Condition, "i", "<", 3)
+	if loop.Post != nil {
+		t.Errorf("loop.Post = %v, want = nil", loop.Post)
+	}
+}
+
+func TestParsingLoopControlOutsideLoop(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"break;", []string{"1:1: break outside of a loop"}},
+		{"if (true) { continue }", []string{"1:13: continue outside of a loop"}},
+		{"while (true) { fn() { break; } }", []string{"1:23: break outside of a loop"}},
+		{"while (true) { [1, if (true) { continue; } else { 2 }] }", []string{"1:32: continue inside an expression whose value is used"}},
+		{"while (true) { let x = if (true) { break } else { 1 }; }", []string{"1:36: break inside an expression whose value is used"}},
+		{"while (true) { if (true) { break } + 1 }", []string{"1:36: + cannot be applied to an expression containing break or continue"}},
+		{"while (true) { [fn() { while (true) { break } }]; if (true) { try { continue } finally { break } } }", []string{}},
+	}
+
+	for _, tc := range testCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		p.ParseProgram()
+		if !reflect.DeepEqual(p.Errors(), tc.expected) {
+			t.Errorf("%s: p.Errors() = %q, want = %q", tc.input, p.Errors(), tc.expected)
+		}
+	}
+}
+
+func TestParsingFloatLiteral(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.25;", 3.25},
+		{"1e3;", 1000},
+		{"5E-1;", 0.5},
+	}
+
+	for _, tc := range testCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Value.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("stmt.Value is not ast.FloatLiteral. got=%T", stmt.Value)
+		}
+		if literal.Value != tc.expected {
+			t.Errorf("literal.Value = %g, want = %g", literal.Value, tc.expected)
+		}
+		if literal.String() != strings.TrimSuffix(tc.input, ";") {
+			t.Errorf("literal.String() = %q, want = %q", literal.String(), strings.TrimSuffix(tc.input, ";"))
+		}
+	}
+
+	p := NewParser(lexer.NewLexer("1e999"))
+	p.ParseProgram()
+	if len(p.Errors()) != 1 || p.Errors()[0] != `1:1: could not parse "1e999" as a 64bit float` {
+		t.Errorf("p.Errors() = %q, want the out of range float reported", p.Errors())
+	}
+}
+
+func TestParserReportsLexerErrors(t *testing.T) {
+	input := `let x = 1; // fine
+let y = x + ; /* unterminated`
+
+	p := NewParser(lexer.NewLexer(input))
+	program := p.ParseProgram()
+
+	expected := []string{
+		"2:13: no prefix parse function registered for ; token",
+		"2:15: unterminated block comment",
+	}
+	if !reflect.DeepEqual(p.Errors(), expected) {
+		t.Errorf("p.Errors() = %q, want = %q", p.Errors(), expected)
+	}
+	if len(program.Statements) != 1 || program.Statements[0].String() != "let x = 1;" {
+		t.Errorf("program.Statements = %v, want [let x = 1;]", program.Statements)
+	}
+}
+
+func TestParserReportsUnterminatedStringOnce(t *testing.T) {
+	input := "let s = \"open;\nlet t = 1;"
+
+	p := NewParser(lexer.NewLexer(input))
+	p.ParseProgram()
+
+	expected := []string{"1:9: unterminated string literal"}
+	if !reflect.DeepEqual(p.Errors(), expected) {
+		t.Errorf("p.Errors() = %q, want = %q", p.Errors(), expected)
+	}
+}
+
+func TestParserDiagnostics(t *testing.T) {
+	p := NewParser(lexer.NewLexer("let x = [1, 2 3];"))
+	p.ParseProgram()
+
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) != 1 {
+		t.Fatalf("len(diagnostics) = %d, want = 1. got = %v", len(diagnostics), p.Errors())
+	}
+
+	d := diagnostics[0]
+	if d.Pos.String() != "1:15" {
+		t.Errorf("d.Pos = %s, want = 1:15", d.Pos)
+	}
+	if d.Severity != SeverityError {
+		t.Errorf("d.Severity = %s, want = %s", d.Severity, SeverityError)
+	}
+	if !reflect.DeepEqual(d.Expected, []token.TokenType{token.COMMA, token.R_SQR_BRACKET}) {
+		t.Errorf("d.Expected = %v, want = [, ]]", d.Expected)
+	}
+	if d.Actual.Type != token.INT || d.Actual.Literal != "3" {
+		t.Errorf("d.Actual = %s %q, want = INT \"3\"", d.Actual.Type, d.Actual.Literal)
+	}
+	if d.Message != "expected next token to be , or ], got INT instead" {
+		t.Errorf("d.Message = %q", d.Message)
+	}
+
+	diagnostics[0].Message = "changed"
+	if p.Diagnostics()[0].Message == "changed" {
+		t.Errorf("p.Diagnostics() returned the parser's own slice")
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	testCases := []struct {
+		input              string
+		expectedErrors     []string
+		expectedStatements []string
+	}{
+		{
+			"let = 5; let y = 2; y",
+			[]string{"1:5: expected next token to be IDENT, got = instead"},
+			[]string{"let y = 2;", "y"},
+		},
+		{
+			"if (x { 1 } let y = ; 2",
+			[]string{
+				"1:7: expected next token to be ), got { instead",
+				"1:21: no prefix parse function registered for ; token",
+			},
+			[]string{"2"},
+		},
+		{
+			"let a = 1 + * 2 let b = 3",
+			[]string{"1:13: no prefix parse function registered for * token"},
+			[]string{"let b = 3;"},
+		},
+		{
+			"let a = (1 + ; let b = 3 * ; let c = 4;",
+			[]string{
+				"1:14: no prefix parse function registered for ; token",
+				"1:28: no prefix parse function registered for ; token",
+			},
+			[]string{"let c = 4;"},
+		},
+		{
+			"let f = fn(x) { let = 1; x + ; x }; f(1)",
+			[]string{
+				"1:21: expected next token to be IDENT, got = instead",
+				"1:30: no prefix parse function registered for ; token",
+			},
+			[]string{"let f = fn<f>(x) x;", "f(1)"},
+		},
+		{
+			"if (x) { x + }; 1",
+			[]string{"1:14: no prefix parse function registered for } token"},
+			[]string{"ifx ", "1"},
+		},
+		{
+			"fn(a, 1) { a }",
+			[]string{"1:7: expected next token to be IDENT, got INT instead"},
+			nil,
+		},
+		{
+			"{\"a\": 1 \"b\": 2}; fn() { 1",
+			[]string{
+				"1:9: expected next token to be , or }, got STRING instead",
+				"1:26: expected } to close the block, got EOF instead",
+			},
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		p := NewParser(lexer.NewLexer(tc.input))
+		program := p.ParseProgram()
+
+		if !reflect.DeepEqual(p.Errors(), tc.expectedErrors) {
+			t.Errorf("input %q: p.Errors() = %q, want = %q", tc.input, p.Errors(), tc.expectedErrors)
+		}
+
+		var statements []string
+		for _, stmt := range program.Statements {
+			statements = append(statements, stmt.String())
+		}
+		if !reflect.DeepEqual(statements, tc.expectedStatements) {
+			t.Errorf("input %q: statements = %q, want = %q", tc.input, statements, tc.expectedStatements)
+		}
+	}
+}
+
+func TestParserErrorsIncludeLocation(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  string
+	}{
+		{"let = 5;", "test.bt:1:5: expected next token to be IDENT, got = instead"},
+		{"let x = 1;\nlet y 2;", "test.bt:2:7: expected next token to be =, got INT instead"},
+		{"let x = 1;\n  * 2;", "test.bt:2:3: no prefix parse function registered for * token"},
+		{"99999999999999999999", "test.bt:1:1: could not parse \"99999999999999999999\" as a 64bit integer"},
+	}
+
+	for _, tc := range testCases {
+		p := NewParser(lexer.NewLexerWithFilename("test.bt", tc.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("input %q: no parser errors, want %q", tc.input, tc.want)
+		}
+		if errors[0] != tc.want {
+			t.Errorf("input %q: errors[0] = %q, want = %q", tc.input, errors[0], tc.want)
+		}
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	input := `let add = fn(a, b) {
+  a + b;
+};
+add(1, [2, 3][0]) * {"k": -x};`
+
+	p := NewParser(lexer.NewLexer(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	let := program.Statements[0].(*ast.LetStatement)
+	fn := let.Value.(*ast.FunctionLiteral)
+	stmt := program.Statements[1].(*ast.ExpressionStatement)
+	product := stmt.Value.(*ast.InfixExpression)
+	call := product.LeftValue.(*ast.CallExpression)
+	index := call.Arguments[1].(*ast.IndexExpression)
+	mapLit := product.RightValue.(*ast.MapLiteral)
+
+	testCases := []struct {
+		node  ast.INode
+		start string
+		end   string
+	}{
+		{program, "1:1", "4:30"},
+		{let, "1:1", "3:2"},
+		{fn, "1:11", "3:2"},
+		{fn.Body, "1:20", "3:2"},
+		{fn.Body.Statements[0], "2:3", "2:8"},
+		{stmt, "4:1", "4:30"},
+		{product, "4:1", "4:30"},
+		{call, "4:1", "4:18"},
+		{index, "4:8", "4:17"},
+		{index.Left, "4:8", "4:14"},
+		{mapLit, "4:21", "4:30"},
+	}
+
+	for i, tc := range testCases {
+		if got := tc.node.Pos().String(); got != tc.start {
+			t.Errorf("testCases[%d] - %T.Pos() = %q, want = %q", i, tc.node, got, tc.start)
+		}
+		if got := tc.node.End().String(); got != tc.end {
+			t.Errorf("testCases[%d] - %T.End() = %q, want = %q", i, tc.node, got, tc.end)
+		}
+	}
+}
+
 func TestParsingFunctionLiteralWithName(t *testing.T) {
 	input := "let funcName = fn() {};"
 
@@ -530,6 +1397,18 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		input    string
 		expected string
 	}{
+		{
+			"a || b && c == d",
+			"(a || (b && (c == d)))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
 		{
 			"-a * b",
 			"((-a) * b)",
@@ -543,6 +1422,14 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"((a + b) + c)",
 		},
 
+		{
+			"a % b * c",
+			"((a % b) * c)",
+		},
+		{
+			"a + b % c",
+			"(a + (b % c))",
+		},
 		{
 			"a * b / c",
 			"((a * b) / c)",
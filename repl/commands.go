@@ -0,0 +1,168 @@
+package repl
+
+import (
+	"BigTalk_Interpreter/compiler"
+	"BigTalk_Interpreter/engine"
+	"BigTalk_Interpreter/lexer"
+	"BigTalk_Interpreter/object"
+	"BigTalk_Interpreter/parser"
+	"BigTalk_Interpreter/token"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const COMMAND_PREFIX = ":"
+
+type command struct {
+	name  string
+	args  string
+	help  string
+	run   func(out io.Writer, eng engine.IEngine, src string)
+	needs string // name of the engine the command depends on, empty if it works with any
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "tokens", args: "<source>", help: "print the tokens produced by the lexer", run: tokensCommand},
+		{name: "ast", args: "<source>", help: "print the statements produced by the parser", run: astCommand},
+		{name: "bytecode", args: "<source>", help: "print the instructions produced by the compiler", run: bytecodeCommand, needs: engine.VMEngine},
+		{name: "constants", help: "print the constant pool of the session", run: constantsCommand, needs: engine.VMEngine},
+		{name: "globals", help: "print the global bindings of the session", run: globalsCommand, needs: engine.VMEngine},
+		{name: "reset", help: "discard every binding of the session", run: resetCommand},
+		{name: "help", help: "print this help", run: helpCommand},
+	}
+}
+
+// runCommand executes a REPL meta-command such as `:ast let x = 1;`. The command name is
+// everything up to the first whitespace, the rest of the input is passed on as its argument.
+func runCommand(out io.Writer, eng engine.IEngine, input string) {
+	input = strings.TrimPrefix(strings.TrimSpace(input), COMMAND_PREFIX)
+	name, src, _ := strings.Cut(input, " ")
+	src = strings.TrimSpace(src)
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		if cmd.needs != "" && cmd.needs != eng.Name() {
+			fmt.Fprintf(out, "%s%s requires the %s engine, the session runs on %s\n",
+				COMMAND_PREFIX, cmd.name, cmd.needs, eng.Name())
+			return
+		}
+
+		if cmd.args != "" && src == "" {
+			fmt.Fprintf(out, "usage: %s%s %s\n", COMMAND_PREFIX, cmd.name, cmd.args)
+			return
+		}
+
+		cmd.run(out, eng, src)
+		return
+	}
+
+	fmt.Fprintf(out, "unknown command %s%s, type %shelp for a list of commands\n", COMMAND_PREFIX, name, COMMAND_PREFIX)
+}
+
+func tokensCommand(out io.Writer, _ engine.IEngine, src string) {
+	l := lexer.NewLexer(src)
+	for tok := l.NextToken(); ; tok = l.NextToken() {
+		for _, comment := range tok.Comments {
+			fmt.Fprintf(out, "%-6s %-10s %q\n", comment.Pos, "COMMENT", comment.Text)
+		}
+		if tok.Type == token.EOF {
+			break
+		}
+		fmt.Fprintf(out, "%-6s %-10s %q\n", tok.Pos, tok.Type, tok.Literal)
+	}
+	for _, err := range l.Errors() {
+		fmt.Fprintf(out, "error: %s\n", err)
+	}
+}
+
+func astCommand(out io.Writer, _ engine.IEngine, src string) {
+	p := parser.NewParser(lexer.NewLexer(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParseErrors(out, p.Errors())
+		return
+	}
+
+	for _, stmt := range program.Statements {
+		fmt.Fprintf(out, "%T %s\n", stmt, stmt)
+	}
+}
+
+func bytecodeCommand(out io.Writer, eng engine.IEngine, src string) {
+	p := parser.NewParser(lexer.NewLexer(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParseErrors(out, p.Errors())
+		return
+	}
+
+	vmEngine := eng.(*engine.VM)
+	bytecode, err := vmEngine.Compile(program)
+	if err != nil {
+		printEngineError(out, err)
+		return
+	}
+
+	io.WriteString(out, bytecode.Instructions.String())
+
+	newConstants := bytecode.Constants[len(vmEngine.Constants()):]
+	if len(newConstants) > 0 {
+		fmt.Fprintln(out, "new constants:")
+		printConstants(out, newConstants, len(vmEngine.Constants()))
+	}
+}
+
+func constantsCommand(out io.Writer, eng engine.IEngine, _ string) {
+	printConstants(out, eng.(*engine.VM).Constants(), 0)
+}
+
+func printConstants(out io.Writer, constants []object.IObject, offset int) {
+	for i, constant := range constants {
+		fmt.Fprintf(out, "%04d %s %s\n", offset+i, constant.Type(), constant.Inspect())
+
+		if fn, ok := constant.(*object.CompiledFunction); ok {
+			for _, line := range strings.Split(strings.TrimSuffix(fn.Instructions.String(), "\n"), "\n") {
+				fmt.Fprintf(out, "     %s\n", line)
+			}
+		}
+	}
+}
+
+func globalsCommand(out io.Writer, eng engine.IEngine, _ string) {
+	vmEngine := eng.(*engine.VM)
+	globals := vmEngine.Globals()
+
+	for _, sym := range vmEngine.SymbolTable().Symbols() {
+		if sym.Scope != compiler.GlobalScope || sym.Hidden() {
+			continue
+		}
+
+		value := "<unset>"
+		if obj := globals[sym.Index]; obj != nil {
+			value = obj.Inspect()
+		}
+		fmt.Fprintf(out, "%04d %s = %s\n", sym.Index, sym.Name, value)
+	}
+}
+
+func resetCommand(out io.Writer, eng engine.IEngine, _ string) {
+	eng.Reset()
+	fmt.Fprintln(out, "session reset")
+}
+
+func helpCommand(out io.Writer, _ engine.IEngine, _ string) {
+	for _, cmd := range commands {
+		usage := COMMAND_PREFIX + cmd.name
+		if cmd.args != "" {
+			usage += " " + cmd.args
+		}
+		fmt.Fprintf(out, "  %-20s %s\n", usage, cmd.help)
+	}
+}
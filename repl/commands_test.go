@@ -0,0 +1,122 @@
+package repl
+
+import (
+	"BigTalk_Interpreter/engine"
+	"BigTalk_Interpreter/lexer"
+	"BigTalk_Interpreter/parser"
+	"bytes"
+	"testing"
+)
+
+func TestRunCommand(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{":tokens let x = 1;", "1:1    LET        \"let\"\n" +
+			"1:5    IDENT      \"x\"\n" +
+			"1:7    =          \"=\"\n" +
+			"1:9    INT        \"1\"\n" +
+			"1:10   ;          \";\"\n"},
+		{":tokens 1 // one", "1:1    INT        \"1\"\n" +
+			"1:3    COMMENT    \"// one\"\n"},
+		{":tokens `a", "1:1    ILLEGAL    \"`a\"\n" +
+			"error: 1:1: unterminated raw string literal\n"},
+		{":ast let x = 1; x + 2", "*ast.LetStatement let x = 1;\n" +
+			"*ast.ExpressionStatement (x + 2)\n"},
+		{":ast let = 1;", "Woops! Parser errors:\n" +
+			"\t1:5: expected next token to be IDENT, got = instead\n"},
+		{"  :tokens   1  ", "1:1    INT        \"1\"\n"},
+		{":tokens", "usage: :tokens <source>\n"},
+		{":ast ", "usage: :ast <source>\n"},
+		{":nope", "unknown command :nope, type :help for a list of commands\n"},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+		runCommand(&out, engine.NewVM(), tc.input)
+
+		if out.String() != tc.expected {
+			t.Errorf("%q: wrong output.\nwant=%q\ngot =%q", tc.input, tc.expected, out.String())
+		}
+	}
+}
+
+func TestRunCommandRequiresEngine(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{":globals", ":globals requires the vm engine, the session runs on eval\n"},
+		{":constants", ":constants requires the vm engine, the session runs on eval\n"},
+		{":bytecode 1", ":bytecode requires the vm engine, the session runs on eval\n"},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+		runCommand(&out, engine.NewEvaluator(), tc.input)
+
+		if out.String() != tc.expected {
+			t.Errorf("%q: wrong output.\nwant=%q\ngot =%q", tc.input, tc.expected, out.String())
+		}
+	}
+}
+
+func TestGlobalsCommand(t *testing.T) {
+	eng := engine.NewVM()
+	runSource(t, eng, `let a = 1; let [b, c] = [2, "three"]; let {"d": d} = {"d": [4]};`)
+
+	var out bytes.Buffer
+	runCommand(&out, eng, ":globals")
+
+	// The destructuring lets store their values in hidden `$n` globals, which are not listed.
+	expected := "0000 a = 1\n" +
+		"0002 b = 2\n" +
+		"0003 c = three\n" +
+		"0004 d = [4]\n"
+	if out.String() != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot =%q", expected, out.String())
+	}
+}
+
+func TestResetCommand(t *testing.T) {
+	for _, name := range engine.Names() {
+		eng, err := engine.New(name)
+		if err != nil {
+			t.Fatalf("engine.New(%q) failed: %s", name, err)
+		}
+		runSource(t, eng, "let x = 1;")
+
+		var out bytes.Buffer
+		runCommand(&out, eng, ":reset")
+		if out.String() != "session reset\n" {
+			t.Errorf("%s: wrong output. got=%q", name, out.String())
+		}
+
+		program := parser.NewParser(lexer.NewLexer("x")).ParseProgram()
+		if _, err := eng.Run(program); err == nil {
+			t.Errorf("%s: x is still defined after :reset", name)
+		}
+
+		if name == engine.VMEngine {
+			out.Reset()
+			runCommand(&out, eng, ":globals")
+			if out.String() != "" {
+				t.Errorf("%s: globals left after :reset. got=%q", name, out.String())
+			}
+		}
+	}
+}
+
+func runSource(t *testing.T, eng engine.IEngine, input string) {
+	t.Helper()
+
+	p := parser.NewParser(lexer.NewLexer(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	if _, err := eng.Run(program); err != nil {
+		t.Fatalf("%s: %s", eng.Name(), err)
+	}
+}
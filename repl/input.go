@@ -0,0 +1,76 @@
+package repl
+
+// inputIncomplete reports whether src stops in the middle of a construct, i.e. it has
+// unbalanced `{`, `(` or `[` delimiters, an unterminated string literal or an unterminated
+// block comment. The REPL keeps reading continuation lines until this returns false and
+// then hands the whole buffer to the parser. Surplus closing delimiters are left for the
+// parser to report. The `${` of an interpolated string counts as an open brace, its
+// expression may span lines and the string resumes after the matching `}`.
+func inputIncomplete(src string) bool {
+	depth := 0
+	commentDepth := 0
+	inString := false
+	inRawString := false
+	var interpolations []int // depth of each open `${`, innermost last
+
+	for i := 0; i < len(src); i++ {
+		chr := src[i]
+		next := byte(0)
+		if i+1 < len(src) {
+			next = src[i+1]
+		}
+
+		switch {
+		case commentDepth > 0:
+			// Nested block comments are an error, but the lexer still matches them up.
+			if chr == '/' && next == '*' {
+				commentDepth++
+				i++
+			} else if chr == '*' && next == '/' {
+				commentDepth--
+				i++
+			}
+			continue
+		case inString:
+			if chr == '\\' {
+				i++
+			} else if chr == '$' && next == '{' {
+				depth++
+				interpolations = append(interpolations, depth)
+				inString = false
+				i++
+			} else if chr == '"' {
+				inString = false
+			}
+			continue
+		case inRawString:
+			if chr == '`' {
+				inRawString = false
+			}
+			continue
+		}
+
+		switch {
+		case chr == '/' && next == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case chr == '/' && next == '*':
+			commentDepth++
+			i++
+		case chr == '"':
+			inString = true
+		case chr == '`':
+			inRawString = true
+		case chr == '{', chr == '(', chr == '[':
+			depth++
+		case chr == '}' && len(interpolations) > 0 && interpolations[len(interpolations)-1] == depth:
+			depth--
+			interpolations = interpolations[:len(interpolations)-1]
+			inString = true
+		case chr == '}', chr == ')', chr == ']':
+			depth--
+		}
+	}
+	return inString || inRawString || commentDepth > 0 || depth > 0
+}
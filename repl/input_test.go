@@ -0,0 +1,47 @@
+package repl
+
+import "testing"
+
+func TestInputIncomplete(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected bool
+	}{
+		{"", false},
+		{"1 + 2", false},
+		{"let f = fn(x) {", true},
+		{"let f = fn(x) {\n x\n};", false},
+		{"add(1,", true},
+		{"[1, 2,\n 3]", false},
+		{`"unterminated`, true},
+		{`"{ not a brace"`, false},
+		{`"(" + "`, true},
+		{`"\" {" + (`, true},
+		{`"\\" + "{"`, false},
+		{"\"unterminated\n{", true},
+		{"\"two\nlines\"", false},
+		{"`raw {", true},
+		{"`raw\n(`", false},
+		{`"Hello ${name`, true},
+		{"\"Hello ${\n name }!\"", false},
+		{`"${ {"a": 1}["a"] } {"`, false},
+		{`"${ "${ x" }`, true},
+		{`"${ "${ x }" }" (`, true},
+		{`"${x}" + "${`, true},
+		{`"$ {"`, false},
+		{"if (x) { 1 } }", false},
+		{"let x = 1; // {", false},
+		{"// \"\nlet f = fn() {", true},
+		{"/* a comment", true},
+		{"/* a comment\n spanning lines */ 1", false},
+		{"/* outer /* inner */", true},
+		{"/* outer /* inner */ */ (", true},
+	}
+
+	for _, tc := range testCases {
+		got := inputIncomplete(tc.input)
+		if got != tc.expected {
+			t.Errorf("inputIncomplete(%q) = %t, want = %t", tc.input, got, tc.expected)
+		}
+	}
+}
@@ -1,61 +1,86 @@
 package repl
 
 import (
-	"BigTalk_Interpreter/compiler"
+	"BigTalk_Interpreter/engine"
 	"BigTalk_Interpreter/lexer"
-	"BigTalk_Interpreter/object"
 	"BigTalk_Interpreter/parser"
-	"BigTalk_Interpreter/vm"
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
 )
 
-const PROMPT = ">> "
+const (
+	PROMPT              = ">> "
+	CONTINUATION_PROMPT = ".. "
+)
 
+// Start runs the REPL on the default engine.
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+	eng, _ := engine.New(engine.DefaultEngine)
+	StartWithEngine(in, out, eng)
+}
 
-	var constants []object.IObject
-	globals := make([]object.IObject, vm.GlobalsSize)
-	symbolTable := compiler.NewSymbolTable()
-	for i, fn := range object.BuiltinFunctions {
-		symbolTable.DefineBuiltin(i, fn.Name)
-	}
+// StartWithEngine runs the REPL on the given engine. Input is buffered across lines until
+// every delimiter and string literal is closed, so multi-line definitions can be typed
+// as-is. Ctrl-D on a partial buffer discards it, Ctrl-D on an empty prompt exits.
+func StartWithEngine(in io.Reader, out io.Writer, eng engine.IEngine) {
+	reader := bufio.NewReader(in)
+	var buffer strings.Builder
 
 	for {
-		fmt.Print(PROMPT)
-		s := scanner.Scan()
-		if !s {
-			return
+		if buffer.Len() == 0 {
+			fmt.Fprint(out, PROMPT)
+		} else {
+			fmt.Fprint(out, CONTINUATION_PROMPT)
 		}
 
-		line := scanner.Text()
-		l := lexer.NewLexer(line)
-		p := parser.NewParser(l)
-
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParseErrors(out, p.Errors())
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			if err != io.EOF || buffer.Len() == 0 {
+				fmt.Fprintln(out)
+				return
+			}
+			// Ctrl-D in the middle of a multi-line input cancels it.
+			buffer.Reset()
+			fmt.Fprintln(out, "\n(input cancelled)")
 			continue
 		}
 
-		comp := compiler.NewCompilerWithState(symbolTable, constants)
-		err := comp.Compile(program)
-		if err != nil {
-			fmt.Fprintf(out, "Compilation error:\n %s\n", err)
+		buffer.WriteString(line)
+		if inputIncomplete(buffer.String()) {
 			continue
 		}
 
-		vMachine := vm.NewVirtualMachineWithGlobalStore(comp.ByteCode(), globals)
-		err = vMachine.Run()
-		if err != nil {
-			fmt.Fprintf(out, "Bytecode execution error:\n %s\n", err)
+		source := buffer.String()
+		buffer.Reset()
+
+		if strings.HasPrefix(strings.TrimSpace(source), COMMAND_PREFIX) {
+			runCommand(out, eng, source)
 			continue
 		}
+		evaluate(out, eng, source)
+	}
+}
+
+func evaluate(out io.Writer, eng engine.IEngine, source string) {
+	l := lexer.NewLexer(source)
+	p := parser.NewParser(l)
 
-		top := vMachine.LastPoppedStackElement()
-		io.WriteString(out, fmt.Sprintf("%s\n", top.Inspect()))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParseErrors(out, p.Errors())
+		return
+	}
+
+	result, err := eng.Run(program)
+	if err != nil {
+		printEngineError(out, err)
+		return
+	}
+
+	if result != nil {
+		io.WriteString(out, fmt.Sprintf("%s\n", result.Inspect()))
 	}
 }
 
@@ -65,3 +90,13 @@ func printParseErrors(out io.Writer, errors []string) {
 		io.WriteString(out, fmt.Sprintf("\t%s\n", msg))
 	}
 }
+
+func printEngineError(out io.Writer, err error) {
+	switch engine.StageOf(err) {
+	case engine.CompileStage:
+		fmt.Fprintf(out, "Compilation error:\n %s\n", err)
+	default:
+		fmt.Fprintf(out, "Runtime error:\n %s\n", err)
+		io.WriteString(out, engine.StackTrace(err))
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"BigTalk_Interpreter/engine"
+	"fmt"
+	"os"
+)
+
+// runCommand implements `bigtalk run <file>`. The whole file is lexed, parsed,
+// compiled and executed in one pass. Any error is written to stderr and reported
+// through a non-zero exit code.
+func runCommand(args []string, defaultEngine string) int {
+	fs := newFlagSet("run")
+	engineName := fs.String("engine", defaultEngine, engineFlagUsage())
+
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(files) != 1 {
+		fmt.Fprintf(os.Stderr, "run: expected exactly one file argument\n\n%s", usage)
+		return 2
+	}
+	path := files[0]
+
+	eng, err := engine.New(*engineName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: %s\n", err)
+		return 2
+	}
+
+	program, ok := parseSourceFile(path)
+	if !ok {
+		return 1
+	}
+
+	_, err = eng.Run(program)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s error: %s\n%s", path, engine.StageOf(err), err, engine.StackTrace(err))
+		return 1
+	}
+	return 0
+}
@@ -9,8 +9,17 @@ const (
 	// Identifiers and literals
 	IDENT  = "IDENT"
 	INT    = "INT"
+	FLOAT  = "FLOAT"
 	STRING = "STRING"
 
+	// Segments of an interpolated string such as "a ${x} b ${y} c": STRING_HEAD holds the
+	// text up to the first `${`, STRING_MID the text between a `}` and the next `${` and
+	// STRING_TAIL the text after the last `}`. The embedded expressions are scanned as
+	// ordinary tokens in between.
+	STRING_HEAD = "STRING_HEAD"
+	STRING_MID  = "STRING_MID"
+	STRING_TAIL = "STRING_TAIL"
+
 	// Operators
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -18,10 +27,22 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
 	LT       = "<"
 	GT       = ">"
 	EQ       = "=="
 	NOT_EQ   = "!="
+	LT_EQ    = "<="
+	GT_EQ    = ">="
+	AND      = "&&"
+	OR       = "||"
+	ARROW    = "=>"
+
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+	PERCENT_ASSIGN  = "%="
 
 	// Delimeters
 	COMMA         = ","
@@ -33,6 +54,7 @@ const (
 	L_SQR_BRACKET = "["
 	R_SQR_BRACKET = "]"
 	COLON         = ":"
+	ELLIPSIS      = "..."
 
 	// Keywords
 	FUNCTION = "FUNCTION"
@@ -42,6 +64,16 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	NULL     = "NULL"
+	MATCH    = "MATCH"
+	THROW    = "THROW"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
 )
 
 type TokenType string
@@ -49,20 +81,70 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // position of the first character of the token
+	End     Position // position immediately after the last character of the token
+
+	Comments []Comment // comments between the previous token and this one
+}
+
+// Comment is a `// line` or `/* block */` comment. Comments are not tokens of their own,
+// the lexer keeps them as trivia on the token that follows them.
+type Comment struct {
+	Text string // full comment text including its delimiters
+	Pos  Position
 }
 
 func (t Token) String() string {
 	return fmt.Sprintf("{Type: %v, Literal: %q}", t.Type, t.Literal)
 }
 
+// Position is a location in a source file. Line and Column start at 1, Column counts
+// characters, Offset is the byte offset from the start of the input. The zero value is an
+// unknown position.
+type Position struct {
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String formats the position as "file:line:column", leaving out the parts that are unknown.
+func (p Position) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"null":     NULL,
+	"match":    MATCH,
+	"throw":    THROW,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
 }
 
 func LookupIdentifier(ident string) TokenType {
@@ -18,3 +18,18 @@ func NewFrame(closure *object.Closure, basePointer int) *Frame {
 func (f *Frame) Instructions() code.Instructions {
 	return f.closure.Fn.Instructions
 }
+
+// traceFrame describes the frame for a stack trace, locating the instruction it is executing.
+// The main frame is the bottom one and runs the top-level program.
+func (f *Frame) traceFrame(main bool) TraceFrame {
+	pos, _ := f.closure.Fn.SourceMap.Lookup(f.ip)
+
+	name := f.closure.Fn.Name
+	switch {
+	case main:
+		name = "<main>"
+	case name == "":
+		name = "<anonymous>"
+	}
+	return TraceFrame{Function: name, Pos: pos}
+}
@@ -0,0 +1,52 @@
+package vm
+
+import (
+	"BigTalk_Interpreter/object"
+)
+
+// handler is an error handler set up by OpTry. It records the frame and the stack pointer
+// of the try statement, both are restored before the handler continues with the error.
+type handler struct {
+	catch       int // instruction offset in the handler's frame to continue at
+	framesIndex int // number of frames when the handler was set up
+	sp          int
+}
+
+// thrownError is the error raised by OpThrow for the value of a throw statement.
+type thrownError struct {
+	value object.IObject
+}
+
+func (e *thrownError) Error() string {
+	return object.ErrorMessage(e.value)
+}
+
+// catch passes err to the innermost handler: the frames called since the handler was set up
+// are dropped, the stack is cut back to where it was and the error value is pushed for the
+// handler to continue with. It reports false when no handler is left.
+func (v *VirtualMachine) catch(err error) bool {
+	if len(v.handlers) == 0 {
+		return false
+	}
+	h := v.handlers[len(v.handlers)-1]
+	v.handlers = v.handlers[:len(v.handlers)-1]
+
+	var stack []string
+	for i := v.framesIndex - 1; i >= h.framesIndex; i-- {
+		stack = append(stack, v.frames[i].traceFrame(false).Function)
+	}
+
+	var value object.IObject
+	if thrown, ok := err.(*thrownError); ok {
+		value = object.ThrownErrorValue(thrown.value, stack)
+	} else {
+		value = object.NewErrorValue(object.RuntimeErrorKind, err.Error(), stack)
+	}
+
+	v.framesIndex = h.framesIndex
+	v.sp = h.sp
+	v.stack[v.sp] = value
+	v.sp++
+	v.currentFrame().ip = h.catch - 1
+	return true
+}
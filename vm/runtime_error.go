@@ -0,0 +1,46 @@
+package vm
+
+import (
+	"BigTalk_Interpreter/token"
+	"fmt"
+	"strings"
+)
+
+// RuntimeError is returned by Run when the program fails. Error only reports the message,
+// the BigTalk call stack at the point of failure is available through StackTrace.
+type RuntimeError struct {
+	Err   error
+	Trace []TraceFrame // innermost call first
+}
+
+// TraceFrame is a single call of a BigTalk stack trace.
+type TraceFrame struct {
+	Function string
+	Pos      token.Position // position of the instruction being executed, invalid when unknown
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// StackTrace renders the call stack, one frame per line.
+func (e *RuntimeError) StackTrace() string {
+	var out strings.Builder
+	for _, frame := range e.Trace {
+		fmt.Fprintf(&out, "  at %s (%s)\n", frame.Function, frame.Pos)
+	}
+	return out.String()
+}
+
+// newRuntimeError captures the current call stack of the VM alongside err.
+func (v *VirtualMachine) newRuntimeError(err error) *RuntimeError {
+	trace := make([]TraceFrame, 0, v.framesIndex)
+	for i := v.framesIndex - 1; i >= 0; i-- {
+		trace = append(trace, v.frames[i].traceFrame(i == 0))
+	}
+	return &RuntimeError{Err: err, Trace: trace}
+}
@@ -4,7 +4,10 @@ import (
 	"BigTalk_Interpreter/code"
 	"BigTalk_Interpreter/compiler"
 	"BigTalk_Interpreter/object"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 )
 
 const (
@@ -28,10 +31,12 @@ type VirtualMachine struct {
 
 	frames      []*Frame
 	framesIndex int
+
+	handlers []handler // handlers of the try statements being executed, innermost last
 }
 
 func NewVirtualMachine(bytecode *compiler.ByteCode) *VirtualMachine {
-	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions, SourceMap: bytecode.SourceMap}
 	mainClosure := &object.Closure{Fn: mainFn}
 	mainFrame := NewFrame(mainClosure, 0)
 
@@ -58,7 +63,29 @@ func (v *VirtualMachine) LastPoppedStackElement() object.IObject {
 	return v.stack[v.sp]
 }
 
-func (v *VirtualMachine) Run() error {
+// Run executes the bytecode. A failing program is reported as a *RuntimeError holding the
+// BigTalk stack trace at the point of failure, unless a try statement catches the error.
+// The compiler never emits instructions that pop an empty stack or the like, but a corrupt
+// bytecode file may hold them, so a panic of the VM is reported as a runtime error as well.
+func (v *VirtualMachine) Run() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = v.newRuntimeError(fmt.Errorf("invalid bytecode: %v", r))
+		}
+	}()
+
+	for {
+		err := v.run()
+		if err == nil {
+			return nil
+		}
+		if !v.catch(err) {
+			return v.newRuntimeError(err)
+		}
+	}
+}
+
+func (v *VirtualMachine) run() error {
 	var ip int
 	var ins code.Instructions
 	var op code.Opcode
@@ -78,7 +105,7 @@ func (v *VirtualMachine) Run() error {
 			if err != nil {
 				return err
 			}
-		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv:
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod:
 			err := v.executeBinaryOperation(op)
 			if err != nil {
 				return err
@@ -95,7 +122,8 @@ func (v *VirtualMachine) Run() error {
 			if err != nil {
 				return err
 			}
-		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan:
+		case code.OpEqual, code.OpNotEqual, code.OpLessThan, code.OpLessThanOrEqual,
+			code.OpGreaterThan, code.OpGreaterThanOrEqual:
 			err := v.executeComparison(op)
 			if err != nil {
 				return err
@@ -124,6 +152,14 @@ func (v *VirtualMachine) Run() error {
 			if !isTruthy(condition) {
 				v.currentFrame().ip = pos - 1
 			}
+		case code.OpJumpTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			v.currentFrame().ip += 2
+
+			condition := v.pop()
+			if isTruthy(condition) {
+				v.currentFrame().ip = pos - 1
+			}
 		case code.OpNull:
 			err := v.push(Null)
 			if err != nil {
@@ -151,6 +187,56 @@ func (v *VirtualMachine) Run() error {
 			if err != nil {
 				return err
 			}
+		case code.OpExtendArray:
+			spread := v.pop()
+			err := v.executeExtendArray(v.stack[v.sp-1], spread)
+			if err != nil {
+				return err
+			}
+		case code.OpMatchArray:
+			itemsCount := int(code.ReadUint16(ins[ip+1:]))
+			hasRest := code.ReadUint8(ins[ip+3:]) == 1
+			v.currentFrame().ip += 3
+
+			array, ok := v.pop().(*object.Array)
+			matched := ok && (len(array.Items) == itemsCount || hasRest && len(array.Items) >= itemsCount)
+			err := v.push(nativeBoolToBooleanObject(matched))
+			if err != nil {
+				return err
+			}
+		case code.OpMatchMap:
+			keysCount := int(code.ReadUint16(ins[ip+1:]))
+			v.currentFrame().ip += 2
+
+			matched := v.matchMap(v.stack[v.sp-keysCount-1], v.stack[v.sp-keysCount:v.sp])
+			v.sp = v.sp - keysCount - 1
+			err := v.push(nativeBoolToBooleanObject(matched))
+			if err != nil {
+				return err
+			}
+		case code.OpTry:
+			catch := int(code.ReadUint16(ins[ip+1:]))
+			v.currentFrame().ip += 2
+
+			v.handlers = append(v.handlers, handler{catch: catch, framesIndex: v.framesIndex, sp: v.sp})
+		case code.OpEndTry:
+			if len(v.handlers) == 0 {
+				return fmt.Errorf("OpEndTry without an active try")
+			}
+			v.handlers = v.handlers[:len(v.handlers)-1]
+		case code.OpThrow:
+			return &thrownError{value: v.pop()}
+		case code.OpConcat:
+			partsCount := int(code.ReadUint16(ins[ip+1:]))
+			v.currentFrame().ip += 2
+
+			str := v.buildString(v.sp-partsCount, v.sp)
+			v.sp = v.sp - partsCount
+
+			err := v.push(str)
+			if err != nil {
+				return err
+			}
 		case code.OpMap:
 			mapLength := int(code.ReadUint16(ins[ip+1:]))
 			v.currentFrame().ip += 2
@@ -173,6 +259,33 @@ func (v *VirtualMachine) Run() error {
 			if err != nil {
 				return err
 			}
+		case code.OpSlice:
+			high := v.pop()
+			low := v.pop()
+			obj := v.pop()
+
+			err := v.executeSlice(obj, low, high)
+			if err != nil {
+				return err
+			}
+		case code.OpSetIndex:
+			value := v.pop()
+			index := v.pop()
+			obj := v.pop()
+
+			err := v.executeSetIndex(obj, index, value)
+			if err != nil {
+				return err
+			}
+		case code.OpDuplicatePair:
+			err := v.push(v.stack[v.sp-2])
+			if err != nil {
+				return err
+			}
+			err = v.push(v.stack[v.sp-2])
+			if err != nil {
+				return err
+			}
 		case code.OpCall:
 			argsCount := code.ReadUint8(ins[ip+1:])
 			v.currentFrame().ip += 1
@@ -181,6 +294,23 @@ func (v *VirtualMachine) Run() error {
 			if err != nil {
 				return err
 			}
+		case code.OpCallSpread:
+			spread := v.pop()
+			args, ok := spread.(*object.Array)
+			if !ok {
+				return fmt.Errorf("spread operand must be ARRAY, got %s", spread.Type())
+			}
+			for _, arg := range args.Items {
+				err := v.push(arg)
+				if err != nil {
+					return err
+				}
+			}
+
+			err := v.executeCall(len(args.Items))
+			if err != nil {
+				return err
+			}
 		case code.OpReturnValue:
 			// first pop return value off the stack
 			returnVal := v.pop()
@@ -205,14 +335,47 @@ func (v *VirtualMachine) Run() error {
 			localIndex := code.ReadUint8(ins[ip+1:])
 			v.currentFrame().ip += 1
 
-			frame := v.currentFrame()
-			v.stack[frame.basePointer+int(localIndex)] = v.pop()
+			slot := v.currentFrame().basePointer + int(localIndex)
+			if cell, ok := v.stack[slot].(*object.Cell); ok {
+				cell.Value = v.pop()
+			} else {
+				v.stack[slot] = v.pop()
+			}
+		case code.OpHasArgument:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			v.currentFrame().ip += 1
+
+			// callClosure clears the slots of the parameters the caller left out.
+			given := v.stack[v.currentFrame().basePointer+int(localIndex)] != nil
+			err := v.push(nativeBoolToBooleanObject(given))
+			if err != nil {
+				return err
+			}
 		case code.OpGetLocal:
 			localIndex := code.ReadUint8(ins[ip+1:])
 			v.currentFrame().ip += 1
 
-			frame := v.currentFrame()
-			err := v.push(v.stack[frame.basePointer+int(localIndex)])
+			value := v.stack[v.currentFrame().basePointer+int(localIndex)]
+			if cell, ok := value.(*object.Cell); ok {
+				value = cell.Value
+			}
+			err := v.push(value)
+			if err != nil {
+				return err
+			}
+		case code.OpGetLocalCell:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			v.currentFrame().ip += 1
+
+			// The local moves into a cell the first time a closure captures it, the slot
+			// keeps the cell so OpGetLocal and OpSetLocal go through it from now on.
+			slot := v.currentFrame().basePointer + int(localIndex)
+			cell, ok := v.stack[slot].(*object.Cell)
+			if !ok {
+				cell = &object.Cell{Value: v.stack[slot]}
+				v.stack[slot] = cell
+			}
+			err := v.push(cell)
 			if err != nil {
 				return err
 			}
@@ -238,6 +401,21 @@ func (v *VirtualMachine) Run() error {
 			freeVariableIndex := code.ReadUint8(ins[ip+1:])
 			v.currentFrame().ip += 1
 
+			currentClosure := v.currentFrame().closure
+			err := v.push(currentClosure.FreeVariables[freeVariableIndex].Value)
+			if err != nil {
+				return err
+			}
+		case code.OpSetFree:
+			freeVariableIndex := code.ReadUint8(ins[ip+1:])
+			v.currentFrame().ip += 1
+
+			currentClosure := v.currentFrame().closure
+			currentClosure.FreeVariables[freeVariableIndex].Value = v.pop()
+		case code.OpGetFreeCell:
+			freeVariableIndex := code.ReadUint8(ins[ip+1:])
+			v.currentFrame().ip += 1
+
 			currentClosure := v.currentFrame().closure
 			err := v.push(currentClosure.FreeVariables[freeVariableIndex])
 			if err != nil {
@@ -279,6 +457,8 @@ func (v *VirtualMachine) executeBinaryOperation(op code.Opcode) error {
 	switch {
 	case leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ:
 		return v.executeBinaryIntegerOperation(op, left, right)
+	case isNumber(left) && isNumber(right):
+		return v.executeBinaryFloatOperation(op, left, right)
 	case leftType == object.STRING_OBJ && rightType == object.STRING_OBJ:
 		return v.executeBinaryStringOperation(op, left, right)
 	default:
@@ -286,10 +466,22 @@ func (v *VirtualMachine) executeBinaryOperation(op code.Opcode) error {
 	}
 }
 
+// executeBinaryIntegerOperation runs integer arithmetic. Division and modulo truncate
+// toward zero, so the remainder takes the sign of the dividend, and a zero divisor is a
+// runtime error.
 func (v *VirtualMachine) executeBinaryIntegerOperation(op code.Opcode, left, right object.IObject) error {
 	leftValue := left.(*object.Integer).Value
 	rightValue := right.(*object.Integer).Value
 
+	if rightValue == 0 {
+		switch op {
+		case code.OpDiv:
+			return fmt.Errorf("division by zero")
+		case code.OpMod:
+			return fmt.Errorf("modulo by zero")
+		}
+	}
+
 	var result int64
 
 	switch op {
@@ -301,12 +493,39 @@ func (v *VirtualMachine) executeBinaryIntegerOperation(op code.Opcode, left, rig
 		result = leftValue * rightValue
 	case code.OpDiv:
 		result = leftValue / rightValue
+	case code.OpMod:
+		result = leftValue % rightValue
 	default:
 		return fmt.Errorf("unknown integer operator: %d", op)
 	}
 	return v.push(&object.Integer{Value: result})
 }
 
+// executeBinaryFloatOperation runs arithmetic where at least one operand is a float, the
+// other one being promoted to float as well.
+func (v *VirtualMachine) executeBinaryFloatOperation(op code.Opcode, left, right object.IObject) error {
+	leftValue, _ := object.ToFloat(left)
+	rightValue, _ := object.ToFloat(right)
+
+	var result float64
+
+	switch op {
+	case code.OpAdd:
+		result = leftValue + rightValue
+	case code.OpSub:
+		result = leftValue - rightValue
+	case code.OpMul:
+		result = leftValue * rightValue
+	case code.OpDiv:
+		result = leftValue / rightValue
+	case code.OpMod:
+		result = math.Mod(leftValue, rightValue)
+	default:
+		return fmt.Errorf("unknown float operator: %d", op)
+	}
+	return v.push(&object.Float{Value: result})
+}
+
 func (v *VirtualMachine) executeBinaryStringOperation(op code.Opcode, left, right object.IObject) error {
 	if op != code.OpAdd {
 		return fmt.Errorf("unknown string operator: %d", op)
@@ -324,6 +543,12 @@ func (v *VirtualMachine) executeComparison(op code.Opcode) error {
 	if left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ {
 		return v.executeIntegerComparison(op, left, right)
 	}
+	if isNumber(left) && isNumber(right) {
+		return v.executeFloatComparison(op, left, right)
+	}
+	if left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ {
+		return v.executeStringComparison(op, left, right)
+	}
 
 	switch op {
 	case code.OpEqual:
@@ -335,6 +560,22 @@ func (v *VirtualMachine) executeComparison(op code.Opcode) error {
 	}
 }
 
+// executeStringComparison compares strings by their contents, two string objects are equal
+// when they hold the same text.
+func (v *VirtualMachine) executeStringComparison(op code.Opcode, left, right object.IObject) error {
+	leftValue := left.(*object.String).Value
+	rightValue := right.(*object.String).Value
+
+	switch op {
+	case code.OpEqual:
+		return v.push(nativeBoolToBooleanObject(leftValue == rightValue))
+	case code.OpNotEqual:
+		return v.push(nativeBoolToBooleanObject(leftValue != rightValue))
+	default:
+		return fmt.Errorf("unknown operator: %d (%s %s)", op, left.Type(), right.Type())
+	}
+}
+
 func (v *VirtualMachine) executeIntegerComparison(op code.Opcode, left, right object.IObject) error {
 	leftValue := left.(*object.Integer).Value
 	rightValue := right.(*object.Integer).Value
@@ -344,8 +585,36 @@ func (v *VirtualMachine) executeIntegerComparison(op code.Opcode, left, right ob
 		return v.push(nativeBoolToBooleanObject(leftValue == rightValue))
 	case code.OpNotEqual:
 		return v.push(nativeBoolToBooleanObject(leftValue != rightValue))
+	case code.OpLessThan:
+		return v.push(nativeBoolToBooleanObject(leftValue < rightValue))
+	case code.OpLessThanOrEqual:
+		return v.push(nativeBoolToBooleanObject(leftValue <= rightValue))
+	case code.OpGreaterThan:
+		return v.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpGreaterThanOrEqual:
+		return v.push(nativeBoolToBooleanObject(leftValue >= rightValue))
+	default:
+		return fmt.Errorf("unknown operator: %d", op)
+	}
+}
+
+func (v *VirtualMachine) executeFloatComparison(op code.Opcode, left, right object.IObject) error {
+	leftValue, _ := object.ToFloat(left)
+	rightValue, _ := object.ToFloat(right)
+
+	switch op {
+	case code.OpEqual:
+		return v.push(nativeBoolToBooleanObject(leftValue == rightValue))
+	case code.OpNotEqual:
+		return v.push(nativeBoolToBooleanObject(leftValue != rightValue))
+	case code.OpLessThan:
+		return v.push(nativeBoolToBooleanObject(leftValue < rightValue))
+	case code.OpLessThanOrEqual:
+		return v.push(nativeBoolToBooleanObject(leftValue <= rightValue))
 	case code.OpGreaterThan:
 		return v.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpGreaterThanOrEqual:
+		return v.push(nativeBoolToBooleanObject(leftValue >= rightValue))
 	default:
 		return fmt.Errorf("unknown operator: %d", op)
 	}
@@ -369,18 +638,22 @@ func (v *VirtualMachine) executeBangOperator() error {
 func (v *VirtualMachine) executeMinusOperator() error {
 	operand := v.pop()
 
-	if operand.Type() != object.INTEGER_OBJ {
+	switch operand := operand.(type) {
+	case *object.Integer:
+		return v.push(&object.Integer{Value: -operand.Value})
+	case *object.Float:
+		return v.push(&object.Float{Value: -operand.Value})
+	default:
 		return fmt.Errorf("unsupported type for -: %s", operand.Type())
 	}
-
-	value := operand.(*object.Integer).Value
-	return v.push(&object.Integer{Value: -value})
 }
 
 func (v *VirtualMachine) executeIndexExpression(obj, index object.IObject) error {
 	switch {
 	case obj.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return v.executeArrayIndex(obj, index)
+	case obj.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return v.executeStringIndex(obj, index)
 	case obj.Type() == object.MAP_OBJ:
 		return v.executeMapIndex(obj, index)
 	default:
@@ -400,6 +673,45 @@ func (v *VirtualMachine) executeArrayIndex(array, index object.IObject) error {
 	return v.push(arrayObj.Items[i])
 }
 
+// executeStringIndex pushes the code point at the index as a string of its own, or null
+// when the index is out of range.
+func (v *VirtualMachine) executeStringIndex(str, index object.IObject) error {
+	runes := []rune(str.(*object.String).Value)
+	i := index.(*object.Integer).Value
+
+	if i < 0 || i >= int64(len(runes)) {
+		return v.push(Null)
+	}
+
+	return v.push(&object.String{Value: string(runes[i])})
+}
+
+// executeSlice pushes the part of a string or array from the low bound up to, but not
+// including, the high bound. Strings are sliced by code points and arrays are copied. A null
+// bound stands for the start or the end, bounds out of range are clamped to it and a low
+// bound past the high bound gives an empty result.
+func (v *VirtualMachine) executeSlice(obj, low, high object.IObject) error {
+	switch obj := obj.(type) {
+	case *object.String:
+		runes := []rune(obj.Value)
+		start, end, err := object.SliceBounds(low, high, len(runes))
+		if err != nil {
+			return err
+		}
+		return v.push(&object.String{Value: string(runes[start:end])})
+	case *object.Array:
+		start, end, err := object.SliceBounds(low, high, len(obj.Items))
+		if err != nil {
+			return err
+		}
+		items := make([]object.IObject, end-start)
+		copy(items, obj.Items[start:end])
+		return v.push(&object.Array{Items: items})
+	default:
+		return fmt.Errorf("slice operator not supported for %s", obj.Type())
+	}
+}
+
 func (v *VirtualMachine) executeMapIndex(hash, index object.IObject) error {
 	mapObj := hash.(*object.Map)
 
@@ -416,6 +728,65 @@ func (v *VirtualMachine) executeMapIndex(hash, index object.IObject) error {
 	return v.push(pair.Value)
 }
 
+// executeSetIndex stores value in an element of an array or map and pushes value. Arrays
+// and maps are mutated in place, every binding holding the same object sees the change.
+func (v *VirtualMachine) executeSetIndex(obj, index, value object.IObject) error {
+	switch obj := obj.(type) {
+	case *object.Array:
+		i, ok := index.(*object.Integer)
+		if !ok {
+			return fmt.Errorf("array index must be INTEGER, got %s", index.Type())
+		}
+		if i.Value < 0 || i.Value >= int64(len(obj.Items)) {
+			return fmt.Errorf("index out of range: %d (array length %d)", i.Value, len(obj.Items))
+		}
+		obj.Items[i.Value] = value
+	case *object.Map:
+		key, ok := index.(object.IHashable)
+		if !ok {
+			return fmt.Errorf("unusable as hash key: %s", index.Type())
+		}
+		obj.Pairs[key.HashKey()] = object.MapPair{Key: index, Value: value}
+	default:
+		return fmt.Errorf("index assignment not supported for %s", obj.Type())
+	}
+	return v.push(value)
+}
+
+// executeExtendArray appends the items of spread to array, an array literal or argument
+// list under construction that nothing else refers to yet.
+func (v *VirtualMachine) executeExtendArray(array, spread object.IObject) error {
+	items, ok := spread.(*object.Array)
+	if !ok {
+		return fmt.Errorf("spread operand must be ARRAY, got %s", spread.Type())
+	}
+	target, ok := array.(*object.Array)
+	if !ok {
+		return fmt.Errorf("cannot extend %s", array.Type())
+	}
+	target.Items = append(target.Items, items.Items...)
+	return nil
+}
+
+// matchMap reports whether obj is a map holding every one of the keys.
+func (v *VirtualMachine) matchMap(obj object.IObject, keys []object.IObject) bool {
+	hash, ok := obj.(*object.Map)
+	if !ok {
+		return false
+	}
+
+	for _, key := range keys {
+		hashable, ok := key.(object.IHashable)
+		if !ok {
+			return false
+		}
+		if _, ok := hash.Pairs[hashable.HashKey()]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func (v *VirtualMachine) buildArray(startIndex, endIndex int) object.IObject {
 	items := make([]object.IObject, endIndex-startIndex)
 
@@ -425,6 +796,17 @@ func (v *VirtualMachine) buildArray(startIndex, endIndex int) object.IObject {
 	return &object.Array{Items: items}
 }
 
+// buildString joins the string forms of the stack elements within the specified range into
+// a new object.String, the result of an interpolated string.
+func (v *VirtualMachine) buildString(startIndex, endIndex int) object.IObject {
+	var out strings.Builder
+
+	for i := startIndex; i < endIndex; i++ {
+		out.WriteString(v.stack[i].Inspect())
+	}
+	return &object.String{Value: out.String()}
+}
+
 // buildMap constructs a new instance of object.Map using the elements from the stack within the specified range.
 // It iterates over the stack starting from startIndex and ending at endIndex, by incrementing the index by 2 in each iteration.
 // For every pair of stack elements at indices i and i+1, it creates a new object.MapPair with the key as the element at index i, and the value as the element at index i+1.
@@ -464,18 +846,34 @@ func (v *VirtualMachine) popFrame() *Frame {
 	return v.frames[v.framesIndex]
 }
 
+// callClosure pushes the frame of a call passing argsCount arguments. The arguments stay
+// where they are as the first local bindings. Parameters the caller left out keep an empty
+// slot, the function prologue fills in their default value, and the arguments left over for
+// a rest parameter are collected into an array placed in the slot after the named ones.
 func (v *VirtualMachine) callClosure(closure *object.Closure, argsCount int) error {
-	if argsCount != closure.Fn.ParametersCount {
-		return fmt.Errorf(
-			"wrong number of arguments: got = %d, want = %d", closure.Fn.ParametersCount, argsCount)
+	fn := closure.Fn
+	err := object.CheckArity(argsCount, fn.ParametersCount, fn.OptionalParametersCount, fn.Variadic)
+	if err != nil {
+		return err
 	}
 
 	frame := NewFrame(closure, v.sp-argsCount)
 	v.pushFrame(frame)
 
+	var rest object.IObject
+	if fn.Variadic {
+		argsCount = min(argsCount, fn.ParametersCount)
+		rest = v.buildArray(frame.basePointer+argsCount, v.sp)
+	}
+
 	// Allocate space for the local bindings on the stack
-	// by increasing the value of the stack pointer (sp)
-	v.sp = frame.basePointer + closure.Fn.LocalsCount
+	// by increasing the value of the stack pointer (sp). The slots are cleared so a cell
+	// left behind by an earlier call cannot be mistaken for a captured local of this one.
+	v.sp = frame.basePointer + fn.LocalsCount
+	clear(v.stack[frame.basePointer+argsCount : v.sp])
+	if rest != nil {
+		v.stack[frame.basePointer+fn.ParametersCount] = rest
+	}
 	return nil
 }
 
@@ -497,6 +895,11 @@ func (v *VirtualMachine) callBuiltin(builtin *object.Builtin, argsCount int) err
 	result := builtin.Fn(args...)
 	v.sp = v.sp - argsCount - 1
 
+	// A builtin reports bad arguments as an error value, raise it like any runtime error.
+	if errObj, ok := result.(*object.Error); ok {
+		return errors.New(errObj.Message)
+	}
+
 	var err error
 	if result != nil {
 		err = v.push(result)
@@ -516,9 +919,16 @@ func (v *VirtualMachine) pushClosure(constIndex int, freeVariablesCount int) err
 		return fmt.Errorf("constant is not *object.CompiledFunction: %+v", constant)
 	}
 
-	free := make([]object.IObject, freeVariablesCount)
+	// Captured variables arrive as cells, anything else, like the enclosing closure itself,
+	// is a value nobody can assign to and gets a cell of its own.
+	free := make([]*object.Cell, freeVariablesCount)
 	for i := 0; i < freeVariablesCount; i++ {
-		free[i] = v.stack[v.sp+i-freeVariablesCount]
+		captured := v.stack[v.sp+i-freeVariablesCount]
+		cell, ok := captured.(*object.Cell)
+		if !ok {
+			cell = &object.Cell{Value: captured}
+		}
+		free[i] = cell
 	}
 	v.sp = v.sp - freeVariablesCount
 
@@ -533,6 +943,10 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	return False
 }
 
+func isNumber(obj object.IObject) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
 func isTruthy(obj object.IObject) bool {
 	switch obj := obj.(type) {
 	case *object.Boolean:
@@ -2,11 +2,15 @@ package vm
 
 import (
 	"BigTalk_Interpreter/ast"
+	"BigTalk_Interpreter/code"
 	"BigTalk_Interpreter/compiler"
 	"BigTalk_Interpreter/lexer"
 	"BigTalk_Interpreter/object"
 	"BigTalk_Interpreter/parser"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -15,6 +19,432 @@ type vmTestCase struct {
 	expected any
 }
 
+// vmErrorTestCase is a program that must stop with a runtime error of the expected message.
+type vmErrorTestCase struct {
+	input    string
+	expected string
+}
+
+func TestVirtualMachineDecodedByteCodeErrors(t *testing.T) {
+	// Instructions only a corrupt bytecode file holds, they pass the checks of UnmarshalBinary.
+	testCases := []struct {
+		instructions []code.Instructions
+		expected     string
+	}{
+		{[]code.Instructions{code.MakeInstruction(code.OpPop)}, "invalid bytecode: runtime error: index out of range [-1]"},
+		{[]code.Instructions{code.MakeInstruction(code.OpTrue), code.MakeInstruction(code.OpCallSpread)},
+			"spread operand must be ARRAY, got BOOLEAN"},
+		{[]code.Instructions{code.MakeInstruction(code.OpEndTry)}, "OpEndTry without an active try"},
+		{[]code.Instructions{code.MakeInstruction(code.OpTrue), code.MakeInstruction(code.OpTrue), code.MakeInstruction(code.OpExtendArray)},
+			"spread operand must be ARRAY, got BOOLEAN"},
+		{[]code.Instructions{code.MakeInstruction(code.OpTrue), code.MakeInstruction(code.OpArray, 0), code.MakeInstruction(code.OpExtendArray)},
+			"cannot extend BOOLEAN"},
+	}
+
+	for _, tc := range testCases {
+		var instructions code.Instructions
+		for _, ins := range tc.instructions {
+			instructions = append(instructions, ins...)
+		}
+
+		data, err := (&compiler.ByteCode{Instructions: instructions}).MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() error: %s", err)
+		}
+		bytecode := &compiler.ByteCode{}
+		err = bytecode.UnmarshalBinary(data)
+		if err != nil {
+			t.Fatalf("UnmarshalBinary() error: %s", err)
+		}
+
+		err = NewVirtualMachine(bytecode).Run()
+		if err == nil {
+			t.Errorf("%s: expected a runtime error, got none", instructions)
+			continue
+		}
+		if err.Error() != tc.expected {
+			t.Errorf("%s: err.Error() = %q, want = %q", instructions, err, tc.expected)
+		}
+	}
+}
+
+func TestVirtualMachineTryExpression(t *testing.T) {
+	testCases := []vmTestCase{
+		{"try { 1 } catch (e) { 2 }", 1},
+		{"try { 1 / 0 } catch (e) { e[\"message\"] }", "division by zero"},
+		{"try { 1 / 0 } catch (e) { e[\"kind\"] }", "RuntimeError"},
+		{"try { len(1) } catch (e) { e[\"message\"] }", "argument to `len` not supported, got INTEGER"},
+		{`try { throw "boom" } catch (e) { e["kind"] + ": " + e["message"] }`, "Error: boom"},
+		{`try { throw {"kind": "Custom", "code": 7} } catch (e) { e["kind"] + "${e["code"]}" }`, "Custom7"},
+		{`try { throw {"message": "m"} } catch (e) { e["kind"] }`, "Error"},
+		{"try { throw [1, 2] } catch { 5 }", 5},
+		{"try { } catch (e) { 1 }", Null},
+		{"try { throw 1 } catch (e) { len(e[\"stack\"]) }", 0},
+		{"let f = fn() { throw 1 }; let g = fn() { f() }; try { g() } catch (e) { e[\"stack\"][0] + e[\"stack\"][1] }", "fg"},
+		{"let f = fn(n) { if (n == 0) { throw 0 } f(n - 1) }; try { f(3) } catch (e) { len(e[\"stack\"]) }", 4},
+		{"let f = fn() { fn() { 1 / 0 }() }; try { f() } catch (e) { e[\"stack\"][0] }", "<anonymous>"},
+		{"let f = fn() { try { throw 1 } catch (e) { throw e[\"message\"] + \"2\" } }; try { f() } catch (e) { e[\"message\"] }", "12"},
+		{"let f = fn() { try { throw {\"kind\": \"K\"} } catch (e) { throw e } }; try { f() } catch (e) { len(e[\"stack\"]) }", 0},
+		{"[1, try { [2, fn() { throw 0 }()] } catch (e) { 3 }, 4]", []int{1, 3, 4}},
+		{"let f = fn() { try { throw 1 } catch (e) { fn() { e[\"message\"] } } }; f()()", "1"},
+		{"let x = 0; try { x = 1 } finally { x = x + 10 }; x", 11},
+		{"let x = 0; try { try { throw 1 } finally { x = 5 } } catch (e) { x * 2 }", 10},
+		{"let x = 0; try { try { throw 1 } catch (e) { throw 2 } finally { x = 5 } } catch (e) { \"${x}\" + e[\"message\"] }", "52"},
+		{"let x = 0; let f = fn() { try { return 1 } finally { x = 7 } }; f() + x", 8},
+		{"let f = fn() { try { return 1 } finally { return 2 } }; f()", 2},
+		{"let f = fn() { try { throw 1 } finally { return 2 } }; f()", 2},
+		{"let log = []; for (let i = 0; i < 4; i += 1) { try { if (i == 1) { continue } if (i == 2) { break } log = push(log, i) } finally { log = push(log, i * 10) } }; log", []int{0, 0, 10, 20}},
+		{"let f = fn() { let i = 0; while (true) { try { i += 1; if (i == 3) { return i } } catch (e) { } } }; f()", 3},
+		{`match (try { throw {"kind": "NotFound"} } catch (e) { e }) { {"kind": "NotFound"} => 1, _ => 2 }`, 1},
+	}
+	runVirtualMachineTests(t, testCases)
+
+	errorCases := []vmErrorTestCase{
+		{`throw "boom"`, "boom"},
+		{`throw {"kind": "K", "message": "m"}`, "m"},
+		{`try { 1 / 0 } finally { 2 }`, "division by zero"},
+		{`try { throw 1 } catch (e) { throw 2 }`, "2"},
+	}
+	runVirtualMachineErrorTests(t, errorCases)
+}
+
+func TestVirtualMachineMatchExpression(t *testing.T) {
+	testCases := []vmTestCase{
+		{"match (0) { 0 => 1, _ => 2 }", 1},
+		{"match (-1) { 1 => 1, -1 => 2 }", 2},
+		{"match (2) { 2.0 => 1, _ => 2 }", 1},
+		{`match ("a" + "b") { "ab" => 1, _ => 2 }`, 1},
+		{"match (null) { false => 1, null => 2 }", 2},
+		{"match (5) { 1 => 1 }", Null},
+		{"match (5) { n => n * 2 }", 10},
+		{"match (5) { _ => 3 }", 3},
+		{"match ([]) { [] => 1, _ => 2 }", 1},
+		{"match ([1, 2]) { [x] => x, [x, y] => x + y }", 3},
+		{"match ([1, 2, 3]) { [x, ...rest] => rest }", []int{2, 3}},
+		{"match ([1]) { [x, ...rest] => len(rest) }", 0},
+		{"match ([1, 2, 3]) { [_, _] => 1, [_, ..._] => 2 }", 2},
+		{"match ([[1, 2], [3]]) { [[a, b], [c]] => [a, b, c] }", []int{1, 2, 3}},
+		{"match ([1, [2]]) { [1, [3]] => 1, [1, [x]] => x }", 2},
+		{"match ([1, 2]) { [x, y] if x > y => 1, [x, y] if x < y => 2 }", 2},
+		{"match (\"[1]\") { [x] => 1, _ => 2 }", 2},
+		{`match ({"type": "circle", "r": 2}) { {"type": "square"} => 1, {"type": "circle", "r": r} => r }`, 2},
+		{`match ({"a": null}) { {"b": _} => 1, {"a": x} => 2 }`, 2},
+		{`match ({"a": 1}) { {} => 1 }`, 1},
+		{`match ([1]) { {} => 1, _ => 2 }`, 2},
+		{`match ({"p": [1, 2]}) { {p: [x, y]} => x + y }`, 3},
+		{`match ({"n": 4}) { {n} if n > 5 => 1, {n} => n }`, 4},
+		{"match (3) { x => { let y = x + 1; y * 2 } }", 8},
+		{"match (3) { x => { } }", Null},
+		{"let f = fn(v) { match (v) { 0 => 1, n => n * f(n - 1) } }; f(5)", 120},
+		{"match (match (1) { 1 => [2] }) { [x] => match (x) { 2 => 3 } }", 3},
+		{"let f = fn(v) { match (v) { [x] => fn() { x } } }; f([7])()", 7},
+		{"match (1) { 1 => 10 } + match (2) { 2 => 20 }", 30},
+	}
+	runVirtualMachineTests(t, testCases)
+}
+
+func TestVirtualMachineDestructuringLet(t *testing.T) {
+	testCases := []vmTestCase{
+		{"let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"let [a, b] = [1]; b", Null},
+		{"let [a, b = a + 1, c = 5] = [1]; [a, b, c]", []int{1, 2, 5}},
+		{"let [a = 9] = [null]; a", 9},
+		{"let [head, ...tail] = [1, 2, 3]; tail", []int{2, 3}},
+		{"let [head, ...tail] = []; tail", []int{}},
+		{"let [[a, b], [c]] = [[1, 2], [3]]; [a, b, c]", []int{1, 2, 3}},
+		{"let [[a, b] = [4, 5]] = []; [a, b]", []int{4, 5}},
+		{`let {title, artist} = {"title": 1, "artist": 2}; [title, artist]`, []int{1, 2}},
+		{`let {x: renamed, y = 7} = {"x": 3}; [renamed, y]`, []int{3, 7}},
+		{`let {"a b": c} = {"a b": 4}; c`, 4},
+		{`let {inner: {v}, list: [first, ...more]} = {"inner": {"v": 1}, "list": [2, 3, 4]}; [v, first, len(more)]`, []int{1, 2, 2}},
+		{"let f = fn(pair) { let [k, v = k * 2] = pair; k + v }; f([3])", 9},
+		{"let x = 1; let [x, y = x] = [5]; y", 5},
+		{"let [a = if (true) { let [z] = [8]; z }, b] = [null, 2]; [a, b]", []int{8, 2}},
+		{"let f = fn(p) { let [a, b] = p; fn() { a += b; a } }; let g = f([1, 10]); g(); g()", 21},
+		{"let s = 0; for (let [i, n] = [0, 4]; i < n; i += 1) { s += i }; s", 6},
+	}
+	runVirtualMachineTests(t, testCases)
+
+	errorCases := []vmErrorTestCase{
+		{"let [a] = 5;", "index operator not supported for INTEGER"},
+		{`let {a} = [1];`, "index operator not supported for ARRAY"},
+		{"let [a = 1 / 0] = [];", "division by zero"},
+	}
+	runVirtualMachineErrorTests(t, errorCases)
+}
+
+func TestVirtualMachineSpread(t *testing.T) {
+	testCases := []vmTestCase{
+		{"let a = [1, 2]; [0, ...a, 3]", []int{0, 1, 2, 3}},
+		{"let a = [1, 2]; [...a, ...a]", []int{1, 2, 1, 2}},
+		{"[...[], ...[]]", []int{}},
+		{"let a = [1, 2]; let b = [...a]; b[0] = 9; a", []int{1, 2}},
+		{"let add = fn(x, y) { x + y }; add(...[3, 4])", 7},
+		{"let add = fn(x, y) { x + y }; add(3, ...[4])", 7},
+		{"let f = fn(a, ...rest) { rest }; f(...[1, 2], 3, ...[4])", []int{2, 3, 4}},
+		{"len(...[[1, 2, 3]])", 3},
+		{"let f = fn(a, b = 5) { a + b }; f(...[1])", 6},
+		{"let f = fn() { let x = 1; fn(...args) { x + len(args) } }; f()(...[1, 2], 3)", 4},
+	}
+	runVirtualMachineTests(t, testCases)
+
+	var args []string
+	for i := 0; i < 300; i++ {
+		args = append(args, strconv.Itoa(i))
+	}
+	runVirtualMachineTests(t, []vmTestCase{
+		{"fn(...all) { len(all) }(" + strings.Join(args, ", ") + ")", 300},
+	})
+
+	errorCases := []vmErrorTestCase{
+		{`[..."abc"]`, "spread operand must be ARRAY, got STRING"},
+		{"let f = fn(x) { x }; f(...{})", "spread operand must be ARRAY, got HASH"},
+		{"fn(a, b) { a }(...[1, 2, 3])", "wrong number of arguments: got = 3, want = 2"},
+	}
+	runVirtualMachineErrorTests(t, errorCases)
+}
+
+func TestVirtualMachineDefaultAndRestParameters(t *testing.T) {
+	testCases := []vmTestCase{
+		{"let f = fn(a, b = 2) { a * b }; f(5)", 10},
+		{"let f = fn(a, b = 2) { a * b }; f(5, 3)", 15},
+		{"let f = fn(a, b = a + 1, c = b * 2) { [a, b, c] }; f(1)", []int{1, 2, 4}},
+		{"let f = fn(a, b = a + 1, c = b * 2) { [a, b, c] }; f(1, 5)", []int{1, 5, 10}},
+		{"let b = 7; let f = fn(a = b, b = 1) { a }; f()", 7},
+		{"let f = fn(a = []) { push(a, 1) }; f(); len(f())", 1},
+		{"let f = fn(first, ...rest) { rest }; f(1, 2, 3)", []int{2, 3}},
+		{"let f = fn(first, ...rest) { len(rest) }; f(1)", 0},
+		{"let f = fn(...all) { all }; f()", []int{}},
+		{"let f = fn(a, b = 10, ...rest) { [a, b, len(rest)] }; f(1, 2, 3, 4)", []int{1, 2, 2}},
+		{"let f = fn(a, b = 10, ...rest) { [a, b, len(rest)] }; f(1)", []int{1, 10, 0}},
+		{"let outer = fn(k) { fn(v = k) { v } }; outer(4)()", 4},
+		{"let sum = fn(n, acc = 0) { if (n == 0) { acc } else { sum(n - 1, acc + n) } }; sum(4)", 10},
+		{"let f = fn(a = 1, ...rest) { fn() { a += len(rest); a } }; let g = f(1, 2, 3); g(); g()", 5},
+		{"let f = fn(a, b = if (true) { let z = a + 5; z }) { [a, b] }; f(1)", []int{1, 6}},
+	}
+	runVirtualMachineTests(t, testCases)
+}
+
+func TestVirtualMachineElseIfAndNull(t *testing.T) {
+	testCases := []vmTestCase{
+		{"if (false) { 1 } else if (true) { 2 } else { 3 }", 2},
+		{"if (false) { 1 } else if (false) { 2 } else { 3 }", 3},
+		{"if (false) { 1 } else if (false) { 2 }", Null},
+		{"if (true) { 1 } else if (1 / 0) { 2 }", 1},
+		{"let n = 75; if (n >= 90) { 1 } else if (n >= 80) { 2 } else if (n >= 70) { 3 } else { 4 }", 3},
+		{"let f = fn(n) { if (n == 0) { 10 } else if (n == 1) { 11 } else { 12 } }; [f(0), f(1), f(2)]", []int{10, 11, 12}},
+		{"if (false) { 1 } else if (true) { let x = 1; }", Null},
+		{"null", Null},
+		{"let x = null; x", Null},
+		{"if (null) { 1 } else { 2 }", 2},
+		{"null == null", true},
+		{"null != 0", true},
+		{"let a = [null]; a[0]", Null},
+		{"let f = fn() { null }; f()", Null},
+	}
+	runVirtualMachineTests(t, testCases)
+}
+
+func TestVirtualMachineUnicodeStrings(t *testing.T) {
+	testCases := []vmTestCase{
+		{`"héllo"[1]`, "é"},
+		{`"a😀b"[1]`, "😀"},
+		{`"héllo"[5]`, Null},
+		{`"héllo"[-1]`, Null},
+		{`"héllo wörld"[1:4]`, "éll"},
+		{`"héllo"[:2]`, "hé"},
+		{`"héllo"[3:]`, "lo"},
+		{`"héllo"[:]`, "héllo"},
+		{`"héllo"[-5:99]`, "héllo"},
+		{`"héllo"[4:2]`, ""},
+		{`let s = "😀😀"; len(s[1:])`, 1},
+		{`let 名前 = "ok"; 名前`, "ok"},
+		{`let a = [1, 2, 3, 4]; a[1:3]`, []int{2, 3}},
+		{`let a = [1, 2, 3]; let b = a[:]; b[0] = 9; a[0]`, 1},
+		{`let f = fn(s) { s[1:len(s) - 1] }; f("«x»")`, "x"},
+		{`[1, 2][2:]`, []int{}},
+	}
+	runVirtualMachineTests(t, testCases)
+
+	errorCases := []vmErrorTestCase{
+		{`[1, 2][:"x"]`, "slice bound must be INTEGER, got STRING"},
+		{`1[1:]`, "slice operator not supported for INTEGER"},
+	}
+	runVirtualMachineErrorTests(t, errorCases)
+}
+
+func TestVirtualMachineInterpolatedStrings(t *testing.T) {
+	testCases := []vmTestCase{
+		{`let name = "Ann"; let age = 30; "Hello ${name}, you are ${age}"`, "Hello Ann, you are 30"},
+		{`"${1 + 2}${2.5}${true}"`, "32.5true"},
+		{`"a ${[1, "b"]} ${{"k": 1}["k"]}"`, "a [1, b] 1"},
+		{`let f = fn(x) { "<${x}>" }; "${f("y")}!"`, "<y>!"},
+		{`let x = if (false) { 1 }; "${x}"`, "null"},
+		{`"${"nested ${1}"} \${literal}"`, "nested 1 ${literal}"},
+		{`let s = ""; for (let i = 0; i < 3; i += 1) { s = "${s}${i}"; } s`, "012"},
+	}
+	runVirtualMachineTests(t, testCases)
+}
+
+func TestVirtualMachineIndexAssignment(t *testing.T) {
+	testCases := []vmTestCase{
+		{"let a = [1, 2, 3]; a[0] = 10; a[0] + a[1]", 12},
+		{"let a = [1, 2, 3]; a[2] = 7", 7},
+		{`let m = {"x": 1}; m["y"] = 2; m["x"] + m["y"]`, 3},
+		{`let m = {}; m[true] = 5; m[true]`, 5},
+		{"let a = [1, 2]; a[1] += 5; a[1]", 7},
+		{`let m = {"n": 2}; m["n"] *= 3; m["n"]`, 6},
+		{"let a = [1]; let b = a; b[0] = 9; a[0]", 9},
+		{"let a = [[0]]; a[0][0] = 4; a[0][0]", 4},
+		{"let i = 0; let next = fn() { i += 1; i }; let a = [0, 0]; a[next()] += 3; [i, a[1]]", []int{1, 3}},
+		{"let a = [1]; let set = fn(arr) { arr[0] = 2 }; set(a); a[0]", 2},
+		{"let f = fn() { let a = [0, 0]; for (let i = 0; i < 2; i += 1) { a[i] = i + 1; } a }; f()", []int{1, 2}},
+		{"let a = [1]; let b = push(a, 2); b[0] = 5; a[0]", 1},
+	}
+	runVirtualMachineTests(t, testCases)
+
+	errorCases := []vmErrorTestCase{
+		{"let a = [1, 2]; a[2] = 0", "index out of range: 2 (array length 2)"},
+		{"let a = [1, 2]; a[-1] = 0", "index out of range: -1 (array length 2)"},
+		{`let a = [1]; a["x"] = 0`, "array index must be INTEGER, got STRING"},
+		{`let m = {}; m[[1]] = 0`, "unusable as hash key: ARRAY"},
+		{`let s = "abc"; s[0] = "x"`, "index assignment not supported for STRING"},
+	}
+	runVirtualMachineErrorTests(t, errorCases)
+}
+
+func TestVirtualMachineAssignExpressions(t *testing.T) {
+	testCases := []vmTestCase{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x = x + 1", 2},
+		{"let a = 1; let b = 2; a = b = 5; a + b", 10},
+		{"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x %= 4", 2},
+		{"let f = fn() { let x = 1; x += 2; x }; f()", 3},
+		{"let n = 0; for (let i = 0; i < 5; i += 1) { n += i; } n", 10},
+		{"let x = 1; let set = fn() { x = 5; }; set(); x", 5},
+		{"let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c(); c()", 3},
+		{"let counter = fn() { let n = 0; fn() { n += 1 } }; let a = counter(); let b = counter(); a(); a(); b()", 1},
+		// the enclosing function sees what the closure assigned and the other way around
+		{"let f = fn() { let x = 1; let set = fn(v) { x = v }; set(4); x }; f()", 4},
+		{"let f = fn() { let x = 1; let g = fn() { x }; x = 2; g() }; f()", 2},
+		{"let f = fn() { let x = 1; let g = fn() { x }; let x = 2; g() }; f()", 2},
+		{"let f = fn(x) { let g = fn() { fn() { x *= 3 } }; g()(); x }; f(2)", 6},
+		{`let f = fn() {
+			let n = 0;
+			let inc = fn() { n += 1 };
+			let get = fn() { n };
+			inc(); inc();
+			[get(), n]
+		};
+		f()`, []int{2, 2}},
+		// a cell from an earlier call must not leak into the locals of the next one
+		{"let f = fn(first) { let x = 0; if (first) { let g = fn() { x }; } x += 1; x }; f(true); f(false)", 1},
+	}
+	runVirtualMachineTests(t, testCases)
+}
+
+func TestVirtualMachineLoops(t *testing.T) {
+	testCases := []vmTestCase{
+		{"let i = 0; while (i < 5) { let i = i + 1; } i", 5},
+		{"let i = 0; while (false) { let i = i + 1; } i", 0},
+		{"let n = 0; for (let i = 0; i < 4; let i = i + 1) { let n = n + i; } n", 6},
+		{"let i = 0; for (;;) { let i = i + 1; if (i == 3) { break; } } i", 3},
+		{"let n = 0; for (let i = 0; i < 10; let i = i + 1) { if (i % 2 == 0) { continue; } let n = n + i; } n", 25},
+		{"let i = 0; while (true) { let i = i + 1; if (i < 100000) { continue } break; } i", 100000},
+		{"let f = fn() { let i = 0; while (true) { let i = i + 1; if (i == 7) { return i * 2; } } }; f()", 14},
+		{"let a = 1; let h = fn() { a }; let a = 5; h()", 5},
+		{"let f = fn(n) { let acc = []; for (let i = 0; i < n; let i = i + 1) { let acc = push(acc, i); } acc }; f(3)", []int{0, 1, 2}},
+		{`let n = 0;
+for (let i = 0; i < 3; let i = i + 1) {
+  for (let j = 0; j < 3; let j = j + 1) {
+    if (j == i) { break; }
+    let n = n + 1;
+  }
+}
+n`, 3},
+		{"if (true) { let x = 1; }", Null},
+		{"if (false) { 1 } else { while (false) {} }", Null},
+	}
+	runVirtualMachineTests(t, testCases)
+}
+
+func TestVirtualMachineDivisionByZero(t *testing.T) {
+	testCases := []vmErrorTestCase{
+		{"1 / 0", "division by zero"},
+		{"1 % 0", "modulo by zero"},
+		{"let f = fn(a, b) { a / b }; f(10, 5 - 5)", "division by zero"},
+	}
+	runVirtualMachineErrorTests(t, testCases)
+}
+
+func TestVirtualMachineLeftToRightEvaluation(t *testing.T) {
+	testCases := []vmErrorTestCase{
+		{`(1 + true) < (2 + "x")`, "unsupported types for binary operation: INTEGER BOOLEAN"},
+		{`(1 + true) >= (2 + "x")`, "unsupported types for binary operation: INTEGER BOOLEAN"},
+		{`(1 + true) && (2 + "x")`, "unsupported types for binary operation: INTEGER BOOLEAN"},
+		{`(1 + true) || (2 + "x")`, "unsupported types for binary operation: INTEGER BOOLEAN"},
+	}
+	runVirtualMachineErrorTests(t, testCases)
+}
+
+func TestVirtualMachineFloatArithmetic(t *testing.T) {
+	testCases := []vmTestCase{
+		{"1.5", 1.5},
+		{"2.5e2", 250.0},
+		{"1E-2", 0.01},
+		{"-0.5", -0.5},
+		{"0.1 + 0.2", 0.30000000000000004},
+		{"1.5 * 2", 3.0},
+		{"2 * 1.5", 3.0},
+		{"7 / 2.0", 3.5},
+		{"7 / 2", 3},
+		{"10 - 0.5", 9.5},
+		{"1 / 0.0", math.Inf(1)},
+		{"7.5 % 2", 1.5},
+		{"-7.5 % 2", -1.5},
+		{"1 == 1.0", true},
+		{"1.0 != 1", false},
+		{"2 > 1.5", true},
+		{"1.5 < 2", true},
+		{"1.5 > 2", false},
+		{"{1: \"one\"}[1.0]", "one"},
+	}
+	runVirtualMachineTests(t, testCases)
+}
+
+func TestVirtualMachineRuntimeErrorStackTrace(t *testing.T) {
+	input := `let add = fn(a, b) {
+  a + b
+};
+let apply = fn(f) { f(1, "x") };
+apply(add);`
+
+	comp := compiler.NewCompiler()
+	err := comp.Compile(parser.NewParser(lexer.NewLexerWithFilename("trace.bt", input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("compile error: %s", err)
+	}
+
+	vm := NewVirtualMachine(comp.ByteCode())
+	err = vm.Run()
+
+	runtimeErr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("err is not *RuntimeError. got = %T (%v)", err, err)
+	}
+	if runtimeErr.Error() != "unsupported types for binary operation: INTEGER STRING" {
+		t.Errorf("runtimeErr.Error() = %q", runtimeErr.Error())
+	}
+
+	want := `  at add (trace.bt:2:5)
+  at apply (trace.bt:4:22)
+  at <main> (trace.bt:5:6)
+`
+	if got := runtimeErr.StackTrace(); got != want {
+		t.Errorf("runtimeErr.StackTrace() = \n%s\nwant = \n%s", got, want)
+	}
+}
+
 func TestVirtualMachineRecursiveFibonacci(t *testing.T) {
 	testCases := []vmTestCase{
 		{
@@ -180,68 +610,50 @@ func TestNewVirtualMachineBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("two")`, 3},
 		{`len("hello world")`, 11},
-		{
-			`len(1)`,
-			&object.Error{
-				Message: "argument to `len` not supported, got INTEGER",
-			},
-		},
-		{`len("one", "two")`,
-			&object.Error{
-				Message: "wrong number of arguments. got=2, want=1",
-			},
-		},
+		{`len("héllo 😀")`, 7},
 		{`len([1, 2, 3])`, 3},
 		{`len([])`, 0},
 		{`print("hello", "world!")`, Null},
 		{`tail([1, 2, 3])`, []int{2, 3}},
 		{`tail([])`, Null},
 		{`push([], 1)`, []int{1}},
-		{`push(1, 1)`,
-			&object.Error{
-				Message: "argument to `push` must be ARRAY, got INTEGER",
-			},
-		},
 	}
 	runVirtualMachineTests(t, testCases)
 }
 
+func TestVirtualMachineBuiltinFunctionErrors(t *testing.T) {
+	testCases := []vmErrorTestCase{
+		{`len(1)`, "argument to `len` not supported, got INTEGER"},
+		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`push(1, 1)`, "argument to `push` must be ARRAY, got INTEGER"},
+	}
+	runVirtualMachineErrorTests(t, testCases)
+}
+
 func TestVirtualMachineCallingFunctionsWithWrongArgs(t *testing.T) {
-	testCases := []vmTestCase{
+	testCases := []vmErrorTestCase{
 		{
 			input:    `fn() { 1; }(1);`,
-			expected: `wrong number of arguments: got = 0, want = 1`,
+			expected: `wrong number of arguments: got = 1, want = 0`,
 		},
 		{
 			input:    `fn(a) { a; }();`,
-			expected: `wrong number of arguments: got = 1, want = 0`,
+			expected: `wrong number of arguments: got = 0, want = 1`,
 		},
 		{
 			input:    `fn(a, b) { a + b; }(1);`,
-			expected: `wrong number of arguments: got = 2, want = 1`,
+			expected: `wrong number of arguments: got = 1, want = 2`,
+		},
+		{
+			input:    `fn(a, b = 2) { a + b; }(1, 2, 3);`,
+			expected: `wrong number of arguments: got = 3, want 1 to 2`,
+		},
+		{
+			input:    `fn(a, b, ...rest) { a + b; }(1);`,
+			expected: `wrong number of arguments: got = 1, want at least 2`,
 		},
 	}
-
-	for _, tc := range testCases {
-		program := parse(tc.input)
-
-		comp := compiler.NewCompiler()
-		err := comp.Compile(program)
-
-		if err != nil {
-			t.Fatalf("compile error: %s", err)
-		}
-
-		vm := NewVirtualMachine(comp.ByteCode())
-		err = vm.Run()
-		if err == nil {
-			t.Fatalf("Expected error from VirtualMachine but got nil")
-		}
-
-		if err.Error() != tc.expected {
-			t.Fatalf("err.Error() = %q, want = %q", err, tc.expected)
-		}
-	}
+	runVirtualMachineErrorTests(t, testCases)
 }
 
 func TestVirtualMachineCallingFunctionsWithArgsAndBindings(t *testing.T) {
@@ -553,6 +965,11 @@ func TestVirtualMachineBooleanExpressions(t *testing.T) {
 	testCases := []vmTestCase{
 		{"true", true},
 		{"false", false},
+		{`"a" == "a"`, true},
+		{`"a" + "b" == "ab"`, true},
+		{`"a" != "a"`, false},
+		{`"a" != "b"`, true},
+		{`"1" == 1`, false},
 		{"true == true", true},
 		{"false == false", true},
 		{"true == false", false},
@@ -577,6 +994,24 @@ func TestVirtualMachineBooleanExpressions(t *testing.T) {
 		{"!!false", false},
 		{"!!3", true},
 		{"!(if (false) { 5; })", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1.5 <= 1", false},
+		{"1 >= 0.5", true},
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"1 && 0", true},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		{"false && (1 + true)", false},
+		{"true || (1 + true)", true},
+		{"true || false && false", true},
 	}
 	runVirtualMachineTests(t, testCases)
 }
@@ -589,6 +1024,11 @@ func TestVirtualMachineIntegerArithmetic(t *testing.T) {
 		{"1 - 2", -1},
 		{"3 * 2", 6},
 		{"6 / 2", 3},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"7 % -3", 1},
+		{"-7 / 2", -3},
+		{"1 + 10 % 4 * 2", 5},
 		{"50 / 2 * 2 + 10 - 5", 55},
 		{"5 + 5 + 5 + 5 - 10", 10},
 		{"2 * 2 * 2 * 2 * 2", 32},
@@ -621,6 +1061,18 @@ func testIntegerObject(expected int64, actual object.IObject) error {
 	return nil
 }
 
+func testFloatObject(expected float64, actual object.IObject) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("actual is not *object.Float. got = %T (%v)", actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object.Value = %g, want = %g", result.Value, expected)
+	}
+	return nil
+}
+
 func testBooleanObject(expected bool, actual object.IObject) error {
 	result, ok := actual.(*object.Boolean)
 	if !ok {
@@ -669,6 +1121,27 @@ func runVirtualMachineTests(t *testing.T, testCases []vmTestCase) {
 	}
 }
 
+func runVirtualMachineErrorTests(t *testing.T, testCases []vmErrorTestCase) {
+	t.Helper()
+
+	for _, tc := range testCases {
+		comp := compiler.NewCompiler()
+		err := comp.Compile(parse(tc.input))
+		if err != nil {
+			t.Fatalf("compile error: %s", err)
+		}
+
+		err = NewVirtualMachine(comp.ByteCode()).Run()
+		if err == nil {
+			t.Errorf("%s: expected a runtime error, got none", tc.input)
+			continue
+		}
+		if err.Error() != tc.expected {
+			t.Errorf("%s: err.Error() = %q, want = %q", tc.input, err, tc.expected)
+		}
+	}
+}
+
 func testExpectedObject(t *testing.T, expected any, actual object.IObject) {
 	t.Helper()
 
@@ -678,6 +1151,11 @@ func testExpectedObject(t *testing.T, expected any, actual object.IObject) {
 		if err != nil {
 			t.Errorf("testIntegerObject() failed: %s", err)
 		}
+	case float64:
+		err := testFloatObject(expected, actual)
+		if err != nil {
+			t.Errorf("testFloatObject() failed: %s", err)
+		}
 	case bool:
 		err := testBooleanObject(bool(expected), actual)
 		if err != nil {